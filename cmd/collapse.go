@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// passedPackagesStatus returns the line which replaces the output of the
+// packages which passed, with --collapse-passing-packages.
+func passedPackagesStatus(exec *testjson.Execution) string {
+	var passed int
+	for _, name := range exec.Packages() {
+		if exec.Package(name).Result() == testjson.ActionPass {
+			passed++
+		}
+	}
+	if passed == 1 {
+		return "1 package passed"
+	}
+	return fmt.Sprintf("%d packages passed", passed)
+}
+
+// printPassedPackages prints the number of packages which passed, because
+// the output of those packages was not printed. On a terminal the status
+// line with the count is removed by the first write after the test output,
+// so the count is always printed as a line of its own.
+func printPassedPackages(opts *Options, out io.Writer, exec *testjson.Execution) {
+	if !opts.CollapsePassingPackages {
+		return
+	}
+	fmt.Fprintln(out, passedPackagesStatus(exec))
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_CollapsePassingPackages(t *testing.T) {
+	defer patchNoColor(true)()
+	script := `echo '{"Action":"pass","Package":"pkg/one","Test":"TestOne"}'
+echo '{"Action":"pass","Package":"pkg/one"}'
+echo '{"Action":"pass","Package":"pkg/two","Test":"TestTwo"}'
+echo '{"Action":"pass","Package":"pkg/two"}'
+printf '%s\n' '{"Action":"output","Package":"pkg/three","Test":"TestThree","Output":"broken\n"}'
+echo '{"Action":"fail","Package":"pkg/three","Test":"TestThree"}'
+echo '{"Action":"fail","Package":"pkg/three"}'
+exit 1`
+	opts := Options{
+		Format:                  "short",
+		RawCommandShell:         true,
+		Args:                    []string{script},
+		CollapsePassingPackages: true,
+		NoSummary:               []string{"failed"},
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.ErrorContains(t, err, "")
+
+	expected := `✖  pkg/three
+=== FAIL: pkg/three TestThree (0.00s)
+broken
+
+2 packages passed
+`
+	assert.Assert(t, cmp.Contains(out.String(), expected), out.String())
+	assert.Assert(t, !bytes.Contains(out.Bytes(), []byte("pkg/one")))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// configFilename is the name of the config file read from the working
+// directory.
+const configFilename = ".gotestsum.yaml"
+
+// flagEnvVars are the functions which return true when the environment sets
+// the default value of a flag. A value from the config file is ignored when
+// the environment sets the value.
+var flagEnvVars = map[string]func() bool{
+	"format":       isEnvSet("GOTESTSUM_FORMAT"),
+	"go-binary":    isEnvSet("GOTESTSUM_GOBINARY"),
+	"jsonfile":     isEnvSet("GOTESTSUM_JSONFILE"),
+	"junitfile":    isEnvSet("GOTESTSUM_JUNITFILE"),
+	"json-summary": isEnvSet("GOTESTSUM_JSON_SUMMARY"),
+	"no-color":     noColorEnvSet,
+	"force-color":  isEnvSet("FORCE_COLOR"),
+}
+
+// loadConfigFile sets the value of flags from a config file. Each line of the
+// file is a flag name and a value separated by a colon, ex: "format: dots".
+// The value of a flag is only set from the config file when the flag was not
+// set on the command line, and the environment variable for the flag is not
+// set. A missing config file is not an error.
+func loadConfigFile(flags *pflag.FlagSet, filename string) error {
+	file, err := os.Open(filename)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "failed to read config file")
+	}
+	defer file.Close() // nolint: errcheck
+
+	values, err := parseConfig(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse %s", filename)
+	}
+	for _, value := range values {
+		if flags.Lookup(value.name) == nil {
+			return errors.Errorf("unknown option %q in %s", value.name, filename)
+		}
+		if flags.Changed(value.name) || isSetByEnv(value.name) {
+			continue
+		}
+		if err := flags.Set(value.name, value.value); err != nil {
+			return errors.Wrapf(err, "invalid value for %s in %s", value.name, filename)
+		}
+	}
+	return nil
+}
+
+type configValue struct {
+	name  string
+	value string
+}
+
+// parseConfig parses the simple subset of YAML used by the config file. Blank
+// lines and comments are ignored. A list value, ex: "[failed, skipped]", is
+// converted to a comma separated value.
+func parseConfig(in io.Reader) ([]configValue, error) {
+	var values []configValue
+	scanner := bufio.NewScanner(in)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("line %d: expected name: value", lineNum)
+		}
+		values = append(values, configValue{
+			name:  strings.TrimSpace(parts[0]),
+			value: parseConfigValue(strings.TrimSpace(parts[1])),
+		})
+	}
+	return values, scanner.Err()
+}
+
+func parseConfigValue(value string) string {
+	if isEnclosed(value, "[", "]") {
+		items := strings.Split(value[1:len(value)-1], ",")
+		for i, item := range items {
+			items[i] = parseConfigValue(strings.TrimSpace(item))
+		}
+		return strings.Join(items, ",")
+	}
+	if isEnclosed(value, `"`, `"`) || isEnclosed(value, "'", "'") {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+func isEnclosed(value, start, end string) bool {
+	return len(value) >= 2 && strings.HasPrefix(value, start) && strings.HasSuffix(value, end)
+}
+
+// isSetByEnv returns true if the environment sets the default value of the
+// flag.
+func isSetByEnv(name string) bool {
+	envSet, ok := flagEnvVars[name]
+	return ok && envSet()
+}
+
+// isEnvSet returns a function which returns true if the environment variable
+// is set.
+func isEnvSet(key string) func() bool {
+	return func() bool {
+		_, ok := os.LookupEnv(key)
+		return ok
+	}
+}
+
+// noColorEnvSet returns true when the environment sets the value of
+// --no-color. It matches noColorFromEnv: an empty NO_COLOR is not set.
+func noColorEnvSet() bool {
+	_, ok := os.LookupEnv("GOTESTSUM_NO_COLOR")
+	return ok || os.Getenv("NO_COLOR") != ""
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	source := `
+# output settings
+format: dots
+junitfile: "junit report.xml"
+no-summary: [skipped, 'failed']
+`
+	values, err := parseConfig(strings.NewReader(source))
+	assert.NilError(t, err)
+	expected := []configValue{
+		{name: "format", value: "dots"},
+		{name: "junitfile", value: "junit report.xml"},
+		{name: "no-summary", value: "skipped,failed"},
+	}
+	assert.DeepEqual(t, values, expected, cmpConfigValue)
+}
+
+func TestParseConfigInvalidLine(t *testing.T) {
+	_, err := parseConfig(strings.NewReader("format: dots\njunitfile\n"))
+	assert.Error(t, err, "line 2: expected name: value")
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, configFilename)
+	source := "format: dots\njunitfile: junit.xml\nno-summary: [skipped]\n"
+	assert.NilError(t, ioutil.WriteFile(filename, []byte(source), 0644))
+
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--format=short-verbose"}))
+	assert.NilError(t, loadConfigFile(flags, filename))
+
+	assert.Equal(t, opts.Format, "short-verbose")
+	assert.Equal(t, opts.JUnitFile, "junit.xml")
+	assert.DeepEqual(t, opts.NoSummary, []string{"skipped"})
+}
+
+func TestLoadConfigFileUnknownOption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, configFilename)
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("formatt: dots\n"), 0644))
+
+	flags, _ := setupFlags("gotestsum")
+	err = loadConfigFile(flags, filename)
+	assert.ErrorContains(t, err, `unknown option "formatt"`)
+}
+
+func TestLoadConfigFileMissing(t *testing.T) {
+	flags, _ := setupFlags("gotestsum")
+	assert.NilError(t, loadConfigFile(flags, "does-not-exist.yaml"))
+}
+
+var cmpConfigValue = gocmp.AllowUnexported(configValue{})
+
+func TestLoadOptions_NoColorFromConfigFile(t *testing.T) {
+	defer patchEnv(map[string]string{})()
+	dir, err := ioutil.TempDir("", "gotestsum-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, configFilename)
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("no-color: false\n"), 0644))
+
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, loadOptions(flags, opts, filename))
+	assert.Equal(t, opts.NoColor, false)
+	assert.Equal(t, opts.ForceColor, false)
+
+	flags, opts = setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse([]string{"--no-color=false"}))
+	assert.NilError(t, loadOptions(flags, opts, filename))
+	assert.Equal(t, opts.ForceColor, true)
+}
+
+func TestLoadConfigFile_ColorEnvPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-config")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, configFilename)
+	source := "force-color: false\nno-color: true\n"
+	assert.NilError(t, ioutil.WriteFile(filename, []byte(source), 0644))
+
+	// FORCE_COLOR from the environment takes precedence over the config file
+	reset := patchEnv(map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"})
+	flags, opts := setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, loadConfigFile(flags, filename))
+	assert.Equal(t, opts.ForceColor, true)
+	reset()
+
+	// an empty NO_COLOR does not set --no-color, so the config file is used
+	defer patchEnv(map[string]string{"NO_COLOR": ""})()
+	flags, opts = setupFlags("gotestsum")
+	assert.NilError(t, flags.Parse(nil))
+	assert.NilError(t, loadConfigFile(flags, filename))
+	assert.Equal(t, opts.NoColor, true)
+}
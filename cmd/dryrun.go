@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// validateDryRun returns an error if --dry-run is used with an option which
+// does not run go test.
+func validateDryRun(opts *Options) error {
+	switch {
+	case !opts.DryRun:
+		return nil
+	case opts.Watch:
+		return errors.New("--dry-run can not be used with --watch")
+	case opts.RawFromFile != "" || opts.JUnitFromFile != "" || opts.MergeJSON:
+		return errors.New("--dry-run requires a go test command, not an input file")
+	}
+	return nil
+}
+
+// runDryRun prints the go test command, with the variables from --env, in a
+// form which can be copied to a shell. Nothing else is done, so the output of
+// the dry run is not hidden by --quiet-on-success, --output-file is not
+// truncated, and go list is not run to find the package path prefix.
+func runDryRun(opts *Options, out io.Writer) error {
+	if err := validateOpts(opts); err != nil {
+		return err
+	}
+	if noPackagesToTest(opts) {
+		log.Warnf("%s does not list any packages, go test would not be run",
+			opts.PackagesFromFile)
+		return nil
+	}
+	_, err := fmt.Fprintln(out, formatCommand(opts))
+	return err
+}
+
+// formatCommand returns the go test command, with the variables from --env,
+// quoted for a shell.
+func formatCommand(opts *Options) string {
+	var words []string
+	for _, env := range opts.Env {
+		words = append(words, shellQuote(env))
+	}
+	for _, arg := range goTestCmdArgs(opts, rerunOpts{}) {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote returns value in single quotes when it contains a character
+// which is special to the shell.
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(value, " \t\n\"'`$\\|&;<>()*?[]{}~#!") {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRun_DryRun(t *testing.T) {
+	opts := Options{
+		Format:   "short",
+		DryRun:   true,
+		GoBinary: "/missing/go",
+		Env:      []string{"GOFLAGS=-mod=vendor -tags=a"},
+		Args:     []string{"-run", "TestA|TestB", "./pkg"},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	expected := "'GOFLAGS=-mod=vendor -tags=a' /missing/go test -json -run 'TestA|TestB' ./pkg\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestRun_DryRunQuietOnSuccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-dry-run")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	outputFile := filepath.Join(dir, "output.txt")
+	assert.NilError(t, ioutil.WriteFile(outputFile, []byte("previous run\n"), 0644))
+
+	opts := Options{
+		Format:         "short",
+		DryRun:         true,
+		QuietOnSuccess: true,
+		OutputFile:     outputFile,
+		GoBinary:       "/missing/go",
+		Args:           []string{"./pkg"},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Equal(t, out.String(), "/missing/go test -json ./pkg\n")
+
+	raw, err := ioutil.ReadFile(outputFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), "previous run\n")
+}
+
+func TestValidateDryRun(t *testing.T) {
+	assert.NilError(t, validateDryRun(&Options{Watch: true}))
+	err := validateDryRun(&Options{DryRun: true, Watch: true})
+	assert.ErrorContains(t, err, "--dry-run can not be used with --watch")
+	err = validateDryRun(&Options{DryRun: true, RawFromFile: "test.json"})
+	assert.ErrorContains(t, err, "--dry-run requires a go test command")
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, shellQuote("./..."), "./...")
+	assert.Equal(t, shellQuote(""), "''")
+	assert.Equal(t, shellQuote("it's"), `'it'\''s'`)
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// Copied from gotestyourself/icmd
+
+// GetExitCode returns the ExitStatus of a process from the error returned by
+// exec.Run(). If the exit status is not available an error is returned.
+func GetExitCode(err error) (int, error) {
+	if exiterr, ok := err.(*exitError); ok {
+		return exiterr.code, nil
+	}
+	if exiterr, ok := err.(*exec.ExitError); ok {
+		if procExit, ok := exiterr.Sys().(syscall.WaitStatus); ok {
+			return procExit.ExitStatus(), nil
+		}
+	}
+	return 0, errors.Wrap(err, "failed to get exit code")
+}
+
+// ExitCodeWithDefault returns ExitStatus of a process from the error returned by
+// exec.Run(). If the exit status is not available return a default of 127.
+func ExitCodeWithDefault(err error) int {
+	if err == nil {
+		return 0
+	}
+	exitCode, exiterr := GetExitCode(err)
+	if exiterr != nil {
+		// we've failed to retrieve exit code, so we set it to 127
+		return 127
+	}
+	return exitCode
+}
+
+// IsExitError returns true if the error has an exit code.
+func IsExitError(err error) bool {
+	_, err = GetExitCode(err)
+	return err == nil
+}
+
+// exitError is returned when the run should exit with a non-zero status
+// code, but there is no go test process exit status to use. The reason for
+// exiting has already been reported, so no error message is printed.
+type exitError struct {
+	code   int
+	reason string
+	// stopped is true when the run was stopped before all the tests ran, by
+	// a signal or by one of the flags which stop the run early.
+	stopped bool
+	// signal is the signal which interrupted the run, or nil if the run was
+	// not interrupted.
+	signal os.Signal
+}
+
+func (e *exitError) Error() string {
+	return e.reason
+}
+
+// isTestFailure returns true if err is the exit code 1 of a test run which
+// was not stopped early, which means that tests failed.
+func isTestFailure(err error) bool {
+	if exitErr, ok := err.(*exitError); ok && exitErr.stopped {
+		return false
+	}
+	return ExitCodeWithDefault(err) == 1
+}
+
+// internalError is an error which is not caused by a failed test run, with
+// the exit code set by --exit-code-on-internal-error.
+type internalError struct {
+	err  error
+	code int
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the original error.
+func (e *internalError) Cause() error {
+	return e.err
+}
+
+// reportError is an error writing one or more reports, like the JUnit XML
+// file, after the tests have run and the summary was printed.
+type reportError struct {
+	errs []error
+}
+
+func (e *reportError) Error() string {
+	msgs := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Cause returns the error of the first report which could not be written.
+// Use Errors for the errors of every report.
+func (e *reportError) Cause() error {
+	return e.errs[0]
+}
+
+// Errors returns the error of each report which could not be written.
+func (e *reportError) Errors() []error {
+	return e.errs
+}
+
+// InternalErrorExitCode returns the exit code to use for an error which does
+// not have an exit code. The default is 3, or 4 when a report could not be
+// written after the tests ran.
+func InternalErrorExitCode(err error) int {
+	switch interr := err.(type) {
+	case *internalError:
+		return interr.code
+	case *reportError:
+		return 4
+	}
+	return 3
+}
+
+// remapExitCode replaces the exit code of a failed run with the exit code from
+// --exit-code-on-failure, and the exit code 3 of an internal error with the
+// exit code from --exit-code-on-internal-error. A value of 0 keeps the default
+// exit code. The exit code of an interrupted run, and the exit code 4 of a
+// report which could not be written, are never replaced.
+func remapExitCode(opts *Options, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case isInterrupted(err):
+		return err
+	case IsExitError(err):
+		if opts.ExitCodeOnFailure == 0 {
+			return err
+		}
+		return &exitError{code: opts.ExitCodeOnFailure, reason: err.Error()}
+	case opts.ExitCodeOnInternalError == 0:
+		return err
+	}
+	if _, ok := err.(*reportError); ok {
+		return err
+	}
+	return &internalError{err: err, code: opts.ExitCodeOnInternalError}
+}
+
+// isInterrupted returns true if err is the exit code of a run which was
+// interrupted by a signal.
+func isInterrupted(err error) bool {
+	exitErr, ok := err.(*exitError)
+	return ok && exitErr.signal != nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// outputMatcher records the lines of test output which match the
+// --fail-on-output pattern. The output is checked as the events are received,
+// because the Execution does not keep the output of tests which passed.
+type outputMatcher struct {
+	pattern *regexp.Regexp
+	matches []outputMatch
+}
+
+type outputMatch struct {
+	pkg  string
+	test string
+	line string
+}
+
+func newOutputMatcher(opts *Options) (*outputMatcher, error) {
+	if opts.FailOnOutput == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(opts.FailOnOutput)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid --fail-on-output")
+	}
+	return &outputMatcher{pattern: pattern}, nil
+}
+
+func validateFailOnOutput(opts *Options) error {
+	_, err := newOutputMatcher(opts)
+	return err
+}
+
+// check records the output of the event if it matches the pattern. Only the
+// output of tests is checked, the lines printed by go test for each test, like
+// === RUN and --- PASS, are ignored.
+func (m *outputMatcher) check(event testjson.TestEvent) {
+	if m == nil || event.Action != testjson.ActionOutput || event.Test == "" {
+		return
+	}
+	line := strings.TrimSuffix(event.Output, "\n")
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+		return
+	}
+	if m.pattern.MatchString(line) {
+		m.matches = append(m.matches, outputMatch{
+			pkg:  event.Package,
+			test: event.Test,
+			line: trimmed,
+		})
+	}
+}
+
+// failOnOutputExitErr prints the lines of test output which matched the
+// --fail-on-output pattern, and returns an error if there were any matches
+// and exitErr is nil. Otherwise exitErr is returned.
+func failOnOutputExitErr(out io.Writer, m *outputMatcher, exitErr error) error {
+	if m == nil || len(m.matches) == 0 {
+		return exitErr
+	}
+	fmt.Fprintln(out, color.RedString("\nTest output matched --fail-on-output:"))
+	for _, match := range m.matches {
+		fmt.Fprintf(out, "%s %s: %s\n",
+			testjson.RelativePackagePath(match.pkg), match.test, match.line)
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return &exitError{code: 1, reason: "test output matched --fail-on-output"}
+}
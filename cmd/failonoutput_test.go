@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_FailOnOutput(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:       "short",
+		RawFromFile:  "testdata/fail-on-output.json",
+		FailOnOutput: "DEPRECATED|PASS",
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Error(t, err, "test output matched --fail-on-output")
+	expected := `
+Test output matched --fail-on-output:
+example.com/pkg TestOld: old_test.go:9: DEPRECATED: use New
+`
+	assert.Assert(t, cmp.Contains(out.String(), expected), out.String())
+
+	opts.FailOnOutput = "TODO"
+	assert.NilError(t, Run(context.Background(), opts, new(bytes.Buffer)))
+}
+
+func TestValidateFailOnOutput(t *testing.T) {
+	assert.NilError(t, validateFailOnOutput(&Options{}))
+	err := validateFailOnOutput(&Options{FailOnOutput: "TODO("})
+	assert.ErrorContains(t, err, "invalid --fail-on-output")
+}
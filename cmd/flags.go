@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// CommandValue is a pflag.Value for a command and its arguments, which are
+// split using shell-like quoting rules.
+type CommandValue struct {
+	original string
+	command  []string
+}
+
+// String returns the original value of the flag.
+func (c *CommandValue) String() string {
+	return c.original
+}
+
+// Set splits raw into a command and its arguments.
+func (c *CommandValue) Set(raw string) error {
+	command, err := splitArgs(raw)
+	if err != nil {
+		return err
+	}
+	c.original = raw
+	c.command = command
+	return nil
+}
+
+// Type returns the name of the type used in the usage text.
+func (c *CommandValue) Type() string {
+	return "command"
+}
+
+// Value returns the command and its arguments.
+func (c *CommandValue) Value() []string {
+	if c == nil {
+		return nil
+	}
+	return c.command
+}
+
+// splitArgs splits a string into arguments using shell-like rules. Arguments
+// are separated by whitespace. Single quotes preserve the literal value of
+// every character. Within double quotes a backslash escapes a double quote or
+// a backslash. Outside of quotes a backslash escapes any character.
+func splitArgs(raw string) ([]string, error) {
+	s := &argSplitter{}
+	for _, r := range raw {
+		s.next(r)
+	}
+	if s.quote != 0 || s.escaped {
+		return nil, errors.Errorf("unterminated quote or escape in %q", raw)
+	}
+	s.endArg()
+	return s.args, nil
+}
+
+type argSplitter struct {
+	args    []string
+	current strings.Builder
+	inArg   bool
+	quote   rune
+	escaped bool
+}
+
+func (s *argSplitter) next(r rune) {
+	switch {
+	case s.escaped:
+		s.escape(r)
+	case s.quote != 0:
+		s.quoted(r)
+	case r == '\\':
+		s.escaped, s.inArg = true, true
+	case r == '\'' || r == '"':
+		s.quote, s.inArg = r, true
+	case unicode.IsSpace(r):
+		s.endArg()
+	default:
+		s.current.WriteRune(r)
+		s.inArg = true
+	}
+}
+
+func (s *argSplitter) escape(r rune) {
+	s.escaped = false
+	if s.quote == '"' && r != '"' && r != '\\' {
+		s.current.WriteRune('\\')
+	}
+	s.current.WriteRune(r)
+}
+
+func (s *argSplitter) quoted(r rune) {
+	switch {
+	case r == s.quote:
+		s.quote = 0
+	case r == '\\' && s.quote == '"':
+		s.escaped = true
+	default:
+		s.current.WriteRune(r)
+	}
+}
+
+func (s *argSplitter) endArg() {
+	if !s.inArg {
+		return
+	}
+	s.args = append(s.args, s.current.String())
+	s.current.Reset()
+	s.inArg = false
+}
+
+// JUnitFieldFormatValue is a pflag.Value for the format of a package path in
+// a JUnit attribute.
+type JUnitFieldFormatValue struct {
+	value string
+}
+
+// String returns the original value of the flag.
+func (f *JUnitFieldFormatValue) String() string {
+	return f.value
+}
+
+// Set validates and sets the format.
+func (f *JUnitFieldFormatValue) Set(val string) error {
+	switch val {
+	case "full", "relative", "short":
+	default:
+		return errors.Errorf("invalid value: %v, must be one of: full, relative, short", val)
+	}
+	f.value = val
+	return nil
+}
+
+// Type returns the name of the type used in the usage text.
+func (f *JUnitFieldFormatValue) Type() string {
+	return "field-format"
+}
+
+// Value returns the function used to format the package path, or nil if no
+// format was set.
+func (f *JUnitFieldFormatValue) Value() junitxml.FormatFunc {
+	switch f.value {
+	case "full":
+		return func(pkgpath string) string { return pkgpath }
+	case "relative":
+		return testjson.RelativePackagePath
+	case "short":
+		return path.Base
+	}
+	return nil
+}
+
+// packagesValue is a pflag.Value for a whitespace separated list of packages.
+type packagesValue []string
+
+func (p *packagesValue) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *packagesValue) Set(raw string) error {
+	*p = append(*p, strings.Fields(raw)...)
+	return nil
+}
+
+func (p *packagesValue) Type() string {
+	return "list"
+}
+
+// noSummaryValue is a pflag.Value for the comma separated list of summary
+// sections hidden by --no-summary. The sections are checked by
+// validateNoSummary when the options are validated, in the same way as the
+// Options.NoSummary set by a caller of Run.
+type noSummaryValue []string
+
+func (v *noSummaryValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *noSummaryValue) Set(raw string) error {
+	for _, value := range strings.Split(raw, ",") {
+		*v = append(*v, strings.TrimSpace(value))
+	}
+	return nil
+}
+
+func (v *noSummaryValue) Type() string {
+	return "list"
+}
+
+// durationsValue is a pflag.Value for a comma separated list of durations.
+type durationsValue []time.Duration
+
+func (d *durationsValue) String() string {
+	values := make([]string, 0, len(*d))
+	for _, value := range *d {
+		values = append(values, value.String())
+	}
+	return strings.Join(values, ",")
+}
+
+func (d *durationsValue) Set(raw string) error {
+	for _, field := range strings.Split(raw, ",") {
+		value, err := time.ParseDuration(strings.TrimSpace(field))
+		if err != nil {
+			return err
+		}
+		*d = append(*d, value)
+	}
+	return nil
+}
+
+func (d *durationsValue) Type() string {
+	return "durations"
+}
+
+// byteSizeValue is a pflag.Value for a number of bytes, with an optional KB,
+// MB, or GB suffix. The suffixes are powers of 1024.
+type byteSizeValue int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{suffix: "GB", size: 1 << 30},
+	{suffix: "MB", size: 1 << 20},
+	{suffix: "KB", size: 1 << 10},
+	{suffix: "B", size: 1},
+}
+
+func (b *byteSizeValue) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSizeValue) Set(raw string) error {
+	value := strings.ToUpper(strings.TrimSpace(raw))
+	unit := int64(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value, unit = strings.TrimSuffix(value, u.suffix), u.size
+			break
+		}
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || size < 0 {
+		return errors.Errorf("invalid size %q, must be a number of bytes with an "+
+			"optional KB, MB, or GB suffix", raw)
+	}
+	*b = byteSizeValue(size * unit)
+	return nil
+}
+
+func (b *byteSizeValue) Type() string {
+	return "size"
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	var testcases = []struct {
+		raw      string
+		expected []string
+	}{
+		{raw: "", expected: nil},
+		{raw: "  notify-send  done ", expected: []string{"notify-send", "done"}},
+		{raw: `say 'it is done'`, expected: []string{"say", "it is done"}},
+		{raw: `say "the \"tests\" are done"`, expected: []string{"say", `the "tests" are done`}},
+		{raw: `run "C:\bin\notify.exe"`, expected: []string{"run", `C:\bin\notify.exe`}},
+		{raw: `echo a\ b ''`, expected: []string{"echo", "a b", ""}},
+		{raw: `echo pre"fix"ed`, expected: []string{"echo", "prefixed"}},
+	}
+	for _, tc := range testcases {
+		actual, err := splitArgs(tc.raw)
+		assert.NilError(t, err, tc.raw)
+		assert.DeepEqual(t, actual, tc.expected)
+	}
+}
+
+func TestSplitArgsUnterminatedQuote(t *testing.T) {
+	_, err := splitArgs(`echo "done`)
+	assert.ErrorContains(t, err, "unterminated quote")
+}
+
+func TestPackagesValue(t *testing.T) {
+	var packages []string
+	value := (*packagesValue)(&packages)
+	assert.NilError(t, value.Set(" ./foo/...  ./bar "))
+	assert.NilError(t, value.Set("./baz"))
+	assert.DeepEqual(t, packages, []string{"./foo/...", "./bar", "./baz"})
+	assert.Equal(t, value.String(), "./foo/... ./bar ./baz")
+}
+
+func TestDurationsValue(t *testing.T) {
+	var value durationsValue
+	assert.NilError(t, value.Set("10ms, 1s"))
+	assert.NilError(t, value.Set("1m"))
+	assert.DeepEqual(t, []time.Duration(value),
+		[]time.Duration{10 * time.Millisecond, time.Second, time.Minute})
+	assert.Equal(t, value.String(), "10ms,1s,1m0s")
+
+	assert.ErrorContains(t, value.Set("fast"), "invalid duration")
+}
+
+func TestByteSizeValue(t *testing.T) {
+	for raw, expected := range map[string]int64{
+		"0":     0,
+		"512":   512,
+		"10B":   10,
+		"2KB":   2048,
+		"100MB": 100 << 20,
+		" 1gb ": 1 << 30,
+		"1 MB":  1 << 20,
+	} {
+		var value byteSizeValue
+		assert.NilError(t, value.Set(raw), raw)
+		assert.Equal(t, int64(value), expected, raw)
+	}
+
+	var value byteSizeValue
+	err := value.Set("ten")
+	assert.Error(t, err,
+		`invalid size "ten", must be a number of bytes with an optional KB, MB, or GB suffix`)
+	assert.ErrorContains(t, value.Set("-1KB"), "invalid size")
+}
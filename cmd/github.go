@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// githubActionsEnabled returns true if annotations should be printed. With
+// a value of auto the annotations are only printed when running in GitHub
+// Actions.
+func githubActionsEnabled(opts *Options) bool {
+	switch opts.GitHubActions {
+	case "always":
+		return true
+	case "auto":
+		return os.Getenv("GITHUB_ACTIONS") == "true"
+	}
+	return false
+}
+
+func validateGitHubActions(value string) error {
+	switch value {
+	case "", "auto", "always":
+		return nil
+	}
+	return errors.Errorf("invalid --github-actions value %s, must be one of: auto, always", value)
+}
+
+// testLocationPattern matches the file and line prefix added by t.Log and
+// t.Error, ex: "    foo_test.go:12: message".
+var testLocationPattern = regexp.MustCompile(`^(\s+)([^\s:]+\.go):(\d+): ?(.*)\n?$`)
+
+// printGitHubAnnotations prints a GitHub Actions error annotation for each
+// failed test. The file and line of the annotation are parsed from the test
+// output. When the output has no file and line a single annotation is
+// printed for the test.
+func printGitHubAnnotations(opts *Options, out io.Writer, execution *testjson.Execution) {
+	failed := execution.Failed()
+	if len(failed) == 0 {
+		return
+	}
+	dirs := packageDirs(opts, failed)
+	for _, tc := range failed {
+		title := strings.TrimSpace(tc.Package + " " + tc.Test)
+		annotations := parseAnnotations(execution.Package(tc.Package).OutputLines(tc))
+		if len(annotations) == 0 {
+			fmt.Fprintf(out, "::error title=%s::%s\n",
+				escapeProperty(title), escapeData(failureMessage(tc)))
+			continue
+		}
+		for _, a := range annotations {
+			fmt.Fprintf(out, "::error file=%s,line=%s,title=%s::%s\n",
+				escapeProperty(filepath.ToSlash(filepath.Join(dirs[tc.Package], a.file))),
+				a.line,
+				escapeProperty(title),
+				escapeData(a.message))
+		}
+	}
+}
+
+func failureMessage(tc testjson.TestCase) string {
+	if tc.Test == "" {
+		return "package failed"
+	}
+	return tc.Test + " failed"
+}
+
+type annotation struct {
+	file    string
+	line    string
+	message string
+}
+
+// parseAnnotations returns an annotation for each line of output with a file
+// and line prefix. Lines which follow, and are indented more than the prefix,
+// are added to the message.
+func parseAnnotations(lines []string) []annotation {
+	var annotations []annotation
+	// indent of the current annotation, empty when there is none
+	var indent string
+	for _, line := range lines {
+		if match := testLocationPattern.FindStringSubmatch(line); match != nil {
+			indent = match[1]
+			annotations = append(annotations, annotation{
+				file:    match[2],
+				line:    match[3],
+				message: match[4],
+			})
+			continue
+		}
+		if indent != "" && isContinuation(line, indent) {
+			a := &annotations[len(annotations)-1]
+			a.message += "\n" + strings.TrimRight(line, "\n")
+			continue
+		}
+		indent = ""
+	}
+	for i := range annotations {
+		annotations[i].message = strings.Trim(annotations[i].message, "\n")
+	}
+	return annotations
+}
+
+func isContinuation(line string, indent string) bool {
+	return strings.HasPrefix(line, indent+" ") || strings.HasPrefix(line, indent+"\t")
+}
+
+// packageDirs returns the directory of each package relative to the working
+// directory, using go list. If go list fails the directories are empty, and
+// the file names from the test output are used without a directory.
+func packageDirs(opts *Options, failed []testjson.TestCase) map[string]string {
+	dirs := make(map[string]string)
+	args := []string{"list", "-f", "{{.ImportPath}} {{.Dir}}"}
+	for _, tc := range failed {
+		if _, ok := dirs[tc.Package]; !ok {
+			dirs[tc.Package] = ""
+			args = append(args, tc.Package)
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return dirs
+	}
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(goBinary(opts), args...)
+	cmd.Stdout = stdout
+	if err := cmd.Run(); err != nil {
+		log.WithError(err).Debug("failed to find package directories")
+		return dirs
+	}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if rel, err := filepath.Rel(cwd, parts[1]); err == nil {
+			dirs[parts[0]] = rel
+		}
+	}
+	return dirs
+}
+
+var (
+	dataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeData(value string) string {
+	return dataEscaper.Replace(value)
+}
+
+func escapeProperty(value string) string {
+	return propertyEscaper.Replace(value)
+}
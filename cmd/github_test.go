@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	lines := []string{
+		"=== RUN   TestSomething\n",
+		"    foo_test.go:12: first failure\n",
+		"        more detail\n",
+		"    foo_test.go:20: \n",
+		"        \tError Trace:\tfoo_test.go:20\n",
+		"        \tError:      \tnot equal\n",
+		"--- FAIL: TestSomething (0.00s)\n",
+		"        not part of a message\n",
+	}
+	expected := []annotation{
+		{file: "foo_test.go", line: "12", message: "first failure\n        more detail"},
+		{
+			file:    "foo_test.go",
+			line:    "20",
+			message: "        \tError Trace:\tfoo_test.go:20\n        \tError:      \tnot equal",
+		},
+	}
+	assert.DeepEqual(t, parseAnnotations(lines), expected, cmpAnnotation)
+}
+
+var cmpAnnotation = gocmp.AllowUnexported(annotation{})
+
+func TestParseAnnotationsNoLocation(t *testing.T) {
+	lines := []string{"panic: oops\n", "FAIL\n"}
+	assert.Assert(t, parseAnnotations(lines) == nil)
+}
+
+func TestEscapeAnnotationValues(t *testing.T) {
+	assert.Equal(t, escapeData("100% done\nnext"), "100%25 done%0Anext")
+	assert.Equal(t, escapeProperty("pkg/a, b: c"), "pkg/a%2C b%3A c")
+}
+
+func TestValidateGitHubActions(t *testing.T) {
+	for _, value := range []string{"", "auto", "always"} {
+		assert.NilError(t, validateGitHubActions(value))
+	}
+	err := validateGitHubActions("never")
+	assert.ErrorContains(t, err, "invalid --github-actions value never")
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// setPackagePathPrefix sets the prefix removed from package paths in the
+// output, using the --format-package-path option. The returned function
+// restores the previous prefix.
+func setPackagePathPrefix(opts *Options) (func(), error) {
+	previous := testjson.PackagePathPrefix()
+	restore := func() { testjson.SetPackagePathPrefix(previous) }
+	switch opts.FormatPackagePath {
+	case "", "relative":
+		return restore, nil
+	case "full":
+		testjson.SetPackagePathPrefix("")
+		return restore, nil
+	case "module":
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		modulePath, err := findModulePath(cwd)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find the module path for --format-package-path")
+		}
+		testjson.SetPackagePathPrefix(modulePath)
+		return restore, nil
+	case "module-relative":
+		modulePath, err := listModulePath(opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find the module path for --format-package-path")
+		}
+		testjson.SetPackagePathPrefix(modulePath)
+		return restore, nil
+	}
+	return nil, errors.Errorf("invalid --format-package-path value %s, "+
+		"must be one of: relative, module, module-relative, full", opts.FormatPackagePath)
+}
+
+// modulePaths caches the output of go list -m for each go binary and
+// directory, so that the go command only runs once when Run is called more
+// than once by the same process.
+var modulePaths = struct {
+	sync.Mutex
+	paths map[string]string
+}{paths: make(map[string]string)}
+
+// listModulePath returns the path of the main module from go list -m. Unlike
+// findModulePath, the go command resolves nested modules and replace
+// directives the same way as go test.
+func listModulePath(opts *Options) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	key := goBinary(opts) + "\x00" + cwd
+	modulePaths.Lock()
+	defer modulePaths.Unlock()
+	if modulePath, ok := modulePaths.paths[key]; ok {
+		return modulePath, nil
+	}
+
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(goBinary(opts), "list", "-m")
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", errors.Wrap(err, "go list -m failed")
+	}
+	// a workspace prints every module, use the first one
+	modulePath := strings.SplitN(strings.TrimSpace(stdout.String()), "\n", 2)[0]
+	if modulePath == "" || modulePath == "command-line-arguments" {
+		return "", errors.New("go list -m did not print a module path")
+	}
+	modulePaths.paths[key] = modulePath
+	return modulePath, nil
+}
+
+// findModulePath returns the module path from the go.mod file in dir, or in
+// the closest parent of dir with a go.mod file.
+func findModulePath(dir string) (string, error) {
+	for {
+		modulePath, err := readModulePath(filepath.Join(dir, "go.mod"))
+		if !os.IsNotExist(err) {
+			return modulePath, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+func readModulePath(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() // nolint: errcheck
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted, nil
+		}
+		return fields[1], nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.Errorf("no module directive in %s", filename)
+}
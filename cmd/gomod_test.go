@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFindModulePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-gomod")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	gomod := "// comment\nmodule \"example.com/org/repo\"\n\ngo 1.13\n"
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644))
+	nested := filepath.Join(dir, "internal", "foo")
+	assert.NilError(t, os.MkdirAll(nested, 0755))
+
+	modulePath, err := findModulePath(nested)
+	assert.NilError(t, err)
+	assert.Equal(t, modulePath, "example.com/org/repo")
+}
+
+func TestFindModulePathNoModuleDirective(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-gomod")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("go 1.13\n"), 0644))
+	_, err = findModulePath(dir)
+	assert.ErrorContains(t, err, "no module directive")
+}
+
+func TestListModulePath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-gomod")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// the fake go binary prints the module path once, so the second call
+	// only succeeds if the module path is cached
+	count := filepath.Join(dir, "count")
+	script := "#!/bin/sh\n" +
+		"test -e " + count + " && exit 1\n" +
+		"touch " + count + "\n" +
+		"echo example.com/org/repo/nested\n"
+	goBinary := filepath.Join(dir, "go")
+	assert.NilError(t, ioutil.WriteFile(goBinary, []byte(script), 0755))
+
+	opts := &Options{GoBinary: goBinary}
+	for i := 0; i < 2; i++ {
+		modulePath, err := listModulePath(opts)
+		assert.NilError(t, err)
+		assert.Equal(t, modulePath, "example.com/org/repo/nested")
+	}
+}
@@ -0,0 +1,486 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/internal/chrometrace"
+	"gotest.tools/gotestsum/internal/jsonsummary"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/internal/mdsummary"
+	"gotest.tools/gotestsum/testjson"
+)
+
+type eventHandler struct {
+	formatter testjson.EventFormatter
+	out       io.Writer
+	err       io.Writer
+	jsonFile  io.WriteCloser
+	// jsonToOut is true when the JSON events are written to the same stream
+	// as out, with --jsonfile=-.
+	jsonToOut bool
+	// midLine is true when the last output written to out did not end with
+	// a newline.
+	midLine bool
+	// progress is set when a status line is printed after the output.
+	progress *progressWriter
+	// collapse is true with --collapse-passing-packages, the status line is
+	// the number of packages which passed.
+	collapse bool
+	// handlers are the Options.EventHandlers, called after each event is
+	// printed.
+	handlers []testjson.EventHandler
+	// failOnOutput is set with --fail-on-output.
+	failOnOutput *outputMatcher
+	// reruns are the tests which were rerun with --rerun-fails.
+	reruns rerunAttempts
+}
+
+func (h *eventHandler) Err(text string) error {
+	if h.progress != nil {
+		if err := h.progress.clear(); err != nil {
+			return err
+		}
+	}
+	if _, err := h.err.Write([]byte(text + "\n")); err != nil {
+		return err
+	}
+	for _, handler := range h.handlers {
+		if err := handler.Err(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *eventHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if err := h.writeJSON(event); err != nil {
+		return errors.Wrap(err, "failed to write JSON file")
+	}
+
+	line, err := h.formatter(event, execution)
+	if err != nil {
+		return errors.Wrap(err, "failed to format event")
+	}
+	if _, err = h.out.Write([]byte(line)); err != nil {
+		return errors.Wrap(err, "failed to write event")
+	}
+	h.failOnOutput.check(event)
+	if line != "" {
+		h.midLine = !strings.HasSuffix(line, "\n")
+	}
+	if h.progress != nil {
+		if err := h.progress.draw(h.status(execution)); err != nil {
+			return errors.Wrap(err, "failed to write event")
+		}
+	}
+	for _, handler := range h.handlers {
+		if err := handler.Event(event, execution); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *eventHandler) status(execution *testjson.Execution) string {
+	if h.collapse {
+		return passedPackagesStatus(execution)
+	}
+	return progressStatus(execution)
+}
+
+// writeJSON writes the event to the JSON file. When the JSON file is out, a
+// newline is written first if the formatted output ended mid-line, so that
+// every JSON event is on a line of its own.
+func (h *eventHandler) writeJSON(event testjson.TestEvent) error {
+	if h.jsonFile == nil {
+		return nil
+	}
+	raw := append(event.Bytes(), '\n')
+	if h.jsonToOut && h.midLine {
+		raw = append([]byte{'\n'}, raw...)
+		h.midLine = false
+	}
+	_, err := h.jsonFile.Write(raw)
+	return err
+}
+
+func (h *eventHandler) Close() error {
+	if h.jsonFile != nil {
+		if err := h.jsonFile.Close(); err != nil {
+			log.WithError(err).Error("failed to close JSON file")
+		}
+	}
+	return nil
+}
+
+var _ testjson.EventHandler = &eventHandler{}
+
+// newEventHandler returns the handler which prints the events to wout, and the
+// stderr of go test to werr. With --jsonfile=- the events are written to
+// rawOut, which is stdout before it is wrapped by the writers which change the
+// output, like --trim-path-prefix, so that the events are not modified.
+func newEventHandler(
+	opts *Options,
+	wout io.Writer,
+	werr io.Writer,
+	rawOut io.Writer,
+) (*eventHandler, error) {
+	formatter, err := newFormatter(opts)
+	if err != nil {
+		return nil, err
+	}
+	handler := &eventHandler{
+		formatter: formatter,
+		out:       wout,
+		err:       werr,
+		handlers:  opts.EventHandlers,
+		collapse:  opts.CollapsePassingPackages,
+		reruns:    make(rerunAttempts),
+	}
+	handler.progress, _ = wout.(*progressWriter)
+	if handler.failOnOutput, err = newOutputMatcher(opts); err != nil {
+		return nil, err
+	}
+	switch opts.JSONFile {
+	case "":
+	case "-":
+		handler.jsonFile = nopWriteCloser{Writer: rawOut}
+		handler.jsonToOut = !opts.OutputToStderr
+	default:
+		handler.jsonFile, err = openJSONFile(opts)
+		if err != nil {
+			return handler, errors.Wrap(err, "failed to open JSON file")
+		}
+	}
+	return handler, nil
+}
+
+// newFormatter returns the EventFormatter for the --format, wrapped by the
+// formatters of the other flags which change the output.
+func newFormatter(opts *Options) (testjson.EventFormatter, error) {
+	formatter := testjson.NewEventFormatterWithOptions(opts.Format, testjson.FormatOptions{
+		MaxLineWidth:      maxLineWidth(opts),
+		DotsPass:          opts.DotsPass,
+		DotsFail:          opts.DotsFail,
+		DotsSkip:          opts.DotsSkip,
+		DotsNoColor:       opts.DotsNoColor,
+		SlowTestThreshold: slowTestThreshold(opts),
+	})
+	if formatter == nil {
+		return nil, errors.Errorf("unknown format %s", opts.Format)
+	}
+	switch {
+	case opts.OnlyFailures:
+		formatter = testjson.OnlyFailures()
+	case opts.HidePassed:
+		formatter = testjson.HidePassed(formatter)
+	}
+	if opts.FocusPackages != "" {
+		focus, err := regexp.Compile(opts.FocusPackages)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid --focus-packages")
+		}
+		formatter = testjson.FocusPackages(formatter, focus)
+	}
+	return groupFormatter(opts, formatter)
+}
+
+// groupFormatter wraps formatter to group the output by package, with
+// --group-output-by or --collapse-passing-packages.
+func groupFormatter(
+	opts *Options,
+	formatter testjson.EventFormatter,
+) (testjson.EventFormatter, error) {
+	includeFailures := !opts.OnlyFailures && !formatPrintsTestOutput(opts.Format)
+	switch opts.GroupOutputBy {
+	case "", "package":
+	default:
+		return nil, errors.Errorf("unknown --group-output-by value %s", opts.GroupOutputBy)
+	}
+	switch {
+	case opts.CollapsePassingPackages:
+		// the output of each package is grouped by both
+		return testjson.CollapsePassingPackages(formatter, includeFailures), nil
+	case opts.GroupOutputBy == "package":
+		return testjson.GroupOutputByPackage(formatter, includeFailures), nil
+	}
+	return formatter, nil
+}
+
+// slowTestThreshold returns the --show-test-times-threshold, or 0 when the
+// elapsed time of slow tests should not be highlighted.
+func slowTestThreshold(opts *Options) time.Duration {
+	if !opts.ShowTestTimes {
+		return 0
+	}
+	return opts.ShowTestTimesThreshold
+}
+
+// openJSONFile opens the --jsonfile. When --jsonfile-max-size is set the file
+// is rotated once it reaches the maximum size.
+func openJSONFile(opts *Options) (io.WriteCloser, error) {
+	if opts.JSONFileMaxSize > 0 {
+		file, err := newRotatingFile(opts.JSONFile, opts.JSONFileMaxSize, opts.Append)
+		if err != nil {
+			return nil, err
+		}
+		return syncJSONFile(opts, file), nil
+	}
+	file, err := createFile(opts.JSONFile, opts.Append)
+	if err != nil {
+		return nil, err
+	}
+	return syncJSONFile(opts, file), nil
+}
+
+// createFile creates or truncates the file, or opens it for appending when
+// appendTo is true.
+func createFile(filename string, appendTo bool) (*os.File, error) {
+	if !appendTo {
+		return os.Create(filename)
+	}
+	return os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// failureLimit is an EventHandler which stops the test run, by calling
+// cancel, once the number of failed tests reaches max, or when a test panics
+// and panics is true. Subtests are not counted because the parent test will
+// also fail.
+type failureLimit struct {
+	testjson.EventHandler
+	max    int
+	count  int
+	panics bool
+	// panicked is the name of the first test which panicked, and panicPkg is
+	// the package of the test.
+	panicked string
+	panicPkg string
+	cancel   func()
+}
+
+func newFailureLimit(
+	handler testjson.EventHandler, max int, panics bool, cancel func(),
+) *failureLimit {
+	return &failureLimit{EventHandler: handler, max: max, panics: panics, cancel: cancel}
+}
+
+func (l *failureLimit) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	switch {
+	case event.Action == testjson.ActionFail && isRootTest(event.Test):
+		l.count++
+	case l.panics && l.panicked == "" && isPanicEvent(event, execution):
+		l.panicked = strings.TrimSpace(
+			testjson.RelativePackagePath(event.Package) + " " + event.Test)
+		l.panicPkg = event.Package
+	}
+	err := l.EventHandler.Event(event, execution)
+	if l.reached() || l.panicEnded(event) {
+		l.cancel()
+	}
+	return err
+}
+
+func (l *failureLimit) reached() bool {
+	return l.max > 0 && l.count >= l.max
+}
+
+// panicEnded returns true if the event is the end of the package with the
+// test which panicked. The run is not stopped until the end of the package,
+// so that the stack and the failure of the test are not lost.
+func (l *failureLimit) panicEnded(event testjson.TestEvent) bool {
+	return l.panicked != "" && event.Package == l.panicPkg &&
+		event.PackageEvent() && event.Action == testjson.ActionFail
+}
+
+// isPanicEvent returns true if the event is the start of the goroutine stack
+// printed after a panic. The output of the test is only checked for a panic
+// when the event starts a goroutine stack.
+func isPanicEvent(event testjson.TestEvent, execution *testjson.Execution) bool {
+	return event.Action == testjson.ActionOutput &&
+		strings.HasPrefix(event.Output, "goroutine ") &&
+		testjson.IsPanicOutput(execution.OutputLines(event.Package, event.Test))
+}
+
+func isRootTest(name string) bool {
+	return name != "" && !strings.Contains(name, "/")
+}
+
+// formatPrintsTestOutput returns true if the format prints the output of
+// failed tests.
+func formatPrintsTestOutput(format string) bool {
+	switch format {
+	case "short", "dots", "pkgname":
+		return false
+	}
+	return true
+}
+
+// writeJUnitFile writes the JUnit XML file. When the filename is - the XML is
+// written to out, after the formatted output and the summary. With --append
+// the testsuites of an existing file are kept.
+func writeJUnitFile(opts *Options, out io.Writer, execution *testjson.Execution) error {
+	switch opts.JUnitFile {
+	case "":
+		return nil
+	case "-":
+		return junitxml.Write(out, execution, junitConfig(opts))
+	}
+	existing, err := readExistingJUnitFile(opts)
+	if err != nil {
+		return err
+	}
+	junitFile, err := os.Create(opts.JUnitFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to open JUnit file")
+	}
+	defer func() {
+		if err := junitFile.Close(); err != nil {
+			log.WithError(err).Error("failed to close JUnit file")
+		}
+	}()
+
+	return junitxml.Append(junitFile, existing, execution, junitConfig(opts))
+}
+
+// readExistingJUnitFile returns the testsuites of the JUnit file with
+// --append, so that they can be written again before the new testsuites. The
+// file is read before it is replaced, so that a file which can not be parsed
+// is left unchanged. A missing or empty file has no testsuites.
+func readExistingJUnitFile(opts *Options) (junitxml.JUnitTestSuites, error) {
+	var suites junitxml.JUnitTestSuites
+	if !opts.Append {
+		return suites, nil
+	}
+	raw, err := ioutil.ReadFile(opts.JUnitFile)
+	switch {
+	case os.IsNotExist(err):
+		return suites, nil
+	case err != nil:
+		return suites, errors.Wrap(err, "failed to read JUnit file")
+	case len(bytes.TrimSpace(raw)) == 0:
+		return suites, nil
+	}
+	suites, err = junitxml.Read(bytes.NewReader(raw))
+	return suites, errors.Wrapf(err, "failed to read existing JUnit file %s", opts.JUnitFile)
+}
+
+// junitStartupError writes a JUnit XML file which reports startErr, when
+// --junitfile-on-startup-error is set, so that CI has a report even when
+// go test could not be started. With --append the testsuites of an existing
+// file are kept. startErr is returned, an error writing the file is only
+// logged.
+func junitStartupError(opts *Options, out io.Writer, startErr error) error {
+	if !opts.JUnitFileOnStartupError {
+		return startErr
+	}
+	existing, err := readExistingJUnitFile(opts)
+	if err != nil {
+		log.WithError(err).Error("failed to write JUnit file")
+		return startErr
+	}
+	if opts.JUnitFile != "-" {
+		junitFile, err := os.Create(opts.JUnitFile)
+		if err != nil {
+			log.WithError(err).Error("failed to open JUnit file")
+			return startErr
+		}
+		defer junitFile.Close() // nolint: errcheck
+		out = junitFile
+	}
+	err = junitxml.AppendStartupError(out, existing, startErr, junitConfig(opts))
+	if err != nil {
+		log.WithError(err).Error("failed to write JUnit file")
+	}
+	return startErr
+}
+
+func junitConfig(opts *Options) junitxml.Config {
+	return junitxml.Config{
+		FormatTestSuiteName:     opts.JUnitTestSuiteNameFormat.Value(),
+		FormatTestCaseClassname: opts.JUnitTestCaseClassnameFormat.Value(),
+		ProjectName:             opts.JUnitProjectName,
+		HideEmptyPackages:       opts.JUnitHideEmptyPackages,
+		Properties:              junitProperties(opts.JUnitProperties),
+	}
+}
+
+// junitProperties converts the KEY=VALUE values of --junitfile-property to
+// JUnit properties.
+func junitProperties(values []string) []junitxml.JUnitProperty {
+	var properties []junitxml.JUnitProperty
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		properties = append(properties, junitxml.JUnitProperty{Name: parts[0], Value: parts[1]})
+	}
+	return properties
+}
+
+func writeJSONSummary(filename string, execution *testjson.Execution) error {
+	if filename == "" {
+		return nil
+	}
+	summaryFile, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open JSON summary file")
+	}
+	defer func() {
+		if err := summaryFile.Close(); err != nil {
+			log.WithError(err).Error("failed to close JSON summary file")
+		}
+	}()
+
+	return jsonsummary.Write(summaryFile, execution)
+}
+
+func writeMarkdownSummary(filename string, execution *testjson.Execution) error {
+	if filename == "" {
+		return nil
+	}
+	summaryFile, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open Markdown summary file")
+	}
+	defer func() {
+		if err := summaryFile.Close(); err != nil {
+			log.WithError(err).Error("failed to close Markdown summary file")
+		}
+	}()
+
+	return mdsummary.Write(summaryFile, execution)
+}
+
+func writeTraceFile(filename string, execution *testjson.Execution) error {
+	if filename == "" {
+		return nil
+	}
+	traceFile, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open trace file")
+	}
+	defer func() {
+		if err := traceFile.Close(); err != nil {
+			log.WithError(err).Error("failed to close trace file")
+		}
+	}()
+
+	return chrometrace.Write(traceFile, execution)
+}
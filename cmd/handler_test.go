@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestEventHandler_JSONFileStdout(t *testing.T) {
+	defer patchNoColor(true)()
+	out := new(bytes.Buffer)
+	opts := &Options{Format: "dots", JSONFile: "-"}
+	handler, err := newEventHandler(opts, out, new(bytes.Buffer), out)
+	assert.NilError(t, err)
+
+	in, err := os.Open("testdata/skipped.json")
+	assert.NilError(t, err)
+	defer in.Close() // nolint: errcheck
+
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  in,
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	var events int
+	for _, line := range strings.Split(out.String(), "\n") {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		events++
+		var event testjson.TestEvent
+		assert.NilError(t, json.Unmarshal([]byte(line), &event), line)
+	}
+	assert.Equal(t, events, 5)
+	assert.Assert(t, strings.Contains(out.String(), "[example.com/pkg]\n{"), out.String())
+}
+
+func TestRun_JSONFileStdoutNotWrapped(t *testing.T) {
+	defer patchNoColor(true)()
+	raw, err := ioutil.ReadFile("testdata/skipped.json")
+	assert.NilError(t, err)
+	expected := strings.Split(strings.TrimSpace(string(raw)), "\n")
+
+	for _, toStderr := range []bool{false, true} {
+		stderr := new(bytes.Buffer)
+		opts := Options{
+			Format:         "short",
+			RawFromFile:    "testdata/skipped.json",
+			JSONFile:       "-",
+			TrimPathPrefix: []string{"example.com"},
+			OutputToStderr: toStderr,
+			stderr:         stderr,
+		}
+		out := new(bytes.Buffer)
+		assert.NilError(t, Run(context.Background(), opts, out))
+
+		var events []string
+		for _, line := range strings.Split(out.String(), "\n") {
+			if strings.HasPrefix(line, "{") {
+				events = append(events, line)
+			}
+		}
+		assert.DeepEqual(t, events, expected)
+		if toStderr {
+			assert.Assert(t, !strings.Contains(stderr.String(), "{"), stderr.String())
+			assert.Assert(t, strings.Contains(stderr.String(), "pkg"), stderr.String())
+		}
+	}
+}
+
+func TestFailureLimit_Panics(t *testing.T) {
+	var cancelled bool
+	limit := newFailureLimit(noopHandler{}, 0, true, func() { cancelled = true })
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestLog"}
+{"Action":"output","Package":"pkg","Test":"TestLog","Output":"panic: only a word\n"}
+{"Action":"fail","Package":"pkg","Test":"TestLog"}
+{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"panic: boom\n"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"\n"}
+{"Action":"output","Package":"pkg","Test":"TestA","Output":"goroutine 7 [running]:\n"}
+{"Action":"fail","Package":"pkg","Test":"TestA"}
+{"Action":"fail","Package":"pkg"}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: limit,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(exec.Failed()), 2)
+	assert.Assert(t, cancelled)
+	assert.Equal(t, limit.panicked, "pkg TestA")
+}
+
+func TestEventHandler_EventHandlers(t *testing.T) {
+	var events []testjson.TestEvent
+	var errs []string
+	opts := &Options{
+		Format: "dots",
+		EventHandlers: []testjson.EventHandler{
+			testjson.EventHandlerFunc(func(event testjson.TestEvent, _ *testjson.Execution) error {
+				events = append(events, event)
+				return nil
+			}),
+			&recordErrHandler{errs: &errs},
+		},
+	}
+	handler, err := newEventHandler(opts, new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer))
+	assert.NilError(t, err)
+
+	in, err := os.Open("testdata/skipped.json")
+	assert.NilError(t, err)
+	defer in.Close() // nolint: errcheck
+
+	_, err = testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  in,
+		Stderr:  strings.NewReader("an error\n"),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 5)
+	assert.DeepEqual(t, errs, []string{"an error"})
+}
+
+type recordErrHandler struct {
+	noopHandler
+	errs *[]string
+}
+
+func (h *recordErrHandler) Err(text string) error {
+	*h.errs = append(*h.errs, text)
+	return nil
+}
+
+func TestJUnitProperties(t *testing.T) {
+	properties := junitProperties([]string{"git.sha=abc123", "build.url=https://ci/1?a=b"})
+	expected := []junitxml.JUnitProperty{
+		{Name: "git.sha", Value: "abc123"},
+		{Name: "build.url", Value: "https://ci/1?a=b"},
+	}
+	assert.DeepEqual(t, properties, expected)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// printSummaryHeader prints the go version, the hostname, and the go test
+// command at the start of the output, so that the output of runs on
+// different machines or toolchains can be compared.
+func printSummaryHeader(opts *Options, out io.Writer) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	fmt.Fprintf(out, "=== %s\n=== host %s\n=== command: %s\n",
+		goVersion(opts), hostname, formatCommand(opts))
+}
+
+// goVersion returns the output of go version, or a placeholder when the go
+// command fails.
+func goVersion(opts *Options) string {
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(goBinary(opts), "version")
+	cmd.Stdout = stdout
+	if err := cmd.Run(); err != nil {
+		log.WithError(err).Debug("failed to run go version")
+		return "go version unknown"
+	}
+	return strings.TrimSpace(stdout.String())
+}
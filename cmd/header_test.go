@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintSummaryHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-header")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	script := "#!/bin/sh\necho go version go1.99 linux/amd64\n"
+	goBinary := filepath.Join(dir, "go")
+	assert.NilError(t, ioutil.WriteFile(goBinary, []byte(script), 0755))
+	hostname, err := os.Hostname()
+	assert.NilError(t, err)
+
+	opts := &Options{GoBinary: goBinary, Args: []string{"-run", "TestA|TestB", "./pkg"}}
+	out := new(bytes.Buffer)
+	printSummaryHeader(opts, out)
+	expected := "=== go version go1.99 linux/amd64\n" +
+		"=== host " + hostname + "\n" +
+		"=== command: " + goBinary + " test -json -run 'TestA|TestB' ./pkg\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestGoVersion_Failed(t *testing.T) {
+	assert.Equal(t, goVersion(&Options{GoBinary: "/missing/go"}), "go version unknown")
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// highlightWriter is an io.Writer which highlights the expected and actual
+// values, and the lines of a diff, in the failure messages printed by
+// testify assertions. Any other output is written unmodified.
+type highlightWriter struct {
+	out io.Writer
+	// midLine is true when the last write did not end with a newline
+	midLine bool
+	inDiff  bool
+}
+
+func newHighlightWriter(out io.Writer) *highlightWriter {
+	return &highlightWriter{out: out}
+}
+
+func (w *highlightWriter) Write(p []byte) (int, error) {
+	lines := strings.SplitAfter(string(p), "\n")
+	var buf strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		complete := strings.HasSuffix(line, "\n")
+		if w.midLine || !complete {
+			buf.WriteString(line)
+		} else {
+			buf.WriteString(w.highlight(line))
+		}
+		w.midLine = !complete
+	}
+	if _, err := io.WriteString(w.out, buf.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// testifyLine matches the continuation lines of a testify failure message,
+// which are indented by a tab, padding the width of the label, and a tab.
+var testifyLine = regexp.MustCompile(`^([ \t]*\t +\t)(.*)\n$`)
+
+func (w *highlightWriter) highlight(line string) string {
+	match := testifyLine.FindStringSubmatch(line)
+	if match == nil {
+		w.inDiff = false
+		return line
+	}
+	prefix, content := match[1], match[2]
+	withColor := w.colorFor(content)
+	if withColor == nil {
+		return line
+	}
+	return prefix + withColor("%s", content) + "\n"
+}
+
+func (w *highlightWriter) colorFor(content string) func(string, ...interface{}) string {
+	switch {
+	case content == "Diff:":
+		w.inDiff = true
+	case strings.HasPrefix(content, "expected:"):
+		return color.RedString
+	case strings.HasPrefix(content, "actual"):
+		return color.GreenString
+	case !w.inDiff:
+	case strings.HasPrefix(content, "---"), strings.HasPrefix(content, "+++"):
+	case strings.HasPrefix(content, "@@"):
+		return color.CyanString
+	case strings.HasPrefix(content, "-"):
+		return color.RedString
+	case strings.HasPrefix(content, "+"):
+		return color.GreenString
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+	"gotest.tools/assert"
+)
+
+func patchNoColor(value bool) func() {
+	orig := color.NoColor
+	color.NoColor = value
+	return func() { color.NoColor = orig }
+}
+
+const testifyFailure = `=== RUN   TestEqual
+--- FAIL: TestEqual (0.00s)
+    	Error Trace:	equal_test.go:12
+    	Error:      	Not equal: 
+    	            	expected: "one"
+    	            	actual  : "two"
+    	            	
+    	            	Diff:
+    	            	--- Expected
+    	            	+++ Actual
+    	            	@@ -1 +1 @@
+    	            	-one
+    	            	+two
+    	Test:       	TestEqual
++ not part of the diff
+`
+
+func TestHighlightWriter(t *testing.T) {
+	defer patchNoColor(false)()
+
+	out := new(bytes.Buffer)
+	w := newHighlightWriter(out)
+	_, err := w.Write([]byte(testifyFailure))
+	assert.NilError(t, err)
+
+	expected := `=== RUN   TestEqual
+--- FAIL: TestEqual (0.00s)
+    	Error Trace:	equal_test.go:12
+    	Error:      	Not equal: 
+    	            	` + color.RedString(`expected: "one"`) + `
+    	            	` + color.GreenString(`actual  : "two"`) + `
+    	            	
+    	            	Diff:
+    	            	--- Expected
+    	            	+++ Actual
+    	            	` + color.CyanString("@@ -1 +1 @@") + `
+    	            	` + color.RedString("-one") + `
+    	            	` + color.GreenString("+two") + `
+    	Test:       	TestEqual
++ not part of the diff
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestHighlightWriterNoColor(t *testing.T) {
+	defer patchNoColor(true)()
+
+	out := new(bytes.Buffer)
+	w := newHighlightWriter(out)
+	_, err := w.Write([]byte(testifyFailure))
+	assert.NilError(t, err)
+	assert.Equal(t, out.String(), testifyFailure)
+}
+
+func TestHighlightWriterPartialLines(t *testing.T) {
+	defer patchNoColor(false)()
+
+	out := new(bytes.Buffer)
+	w := newHighlightWriter(out)
+	for _, chunk := range []string{"··", "✖", "\n", "    \t  \texpected: 1\n"} {
+		_, err := w.Write([]byte(chunk))
+		assert.NilError(t, err)
+	}
+	expected := "··✖\n    \t  \t" + color.RedString("expected: 1") + "\n"
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/internal/junitxml"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// openJUnitFile reads the JUnit XML file, and returns the test2json events for
+// the testcases in the file, so that the file can be read in the same way as
+// --raw-from-file.
+func openJUnitFile(filename string) (io.ReadCloser, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open JUnit file")
+	}
+	defer in.Close() // nolint: errcheck
+
+	suites, err := junitxml.Read(in)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	events, err := junitEvents(suites)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", filename)
+	}
+	return ioutil.NopCloser(events), nil
+}
+
+// junitEvents converts the testsuites to test2json events. Each testsuite is
+// a package, and each testcase is a test in the package.
+func junitEvents(suites junitxml.JUnitTestSuites) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	for _, suite := range suites.Suites {
+		pkgAction := testjson.ActionPass
+		if len(suite.TestCases) == 0 {
+			pkgAction = testjson.ActionSkip
+		}
+		for _, tc := range suite.TestCases {
+			events, err := junitTestCaseEvents(suite.Name, tc)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range events {
+				if event.Action == testjson.ActionFail {
+					pkgAction = testjson.ActionFail
+				}
+				if err := encoder.Encode(event); err != nil {
+					return nil, err
+				}
+			}
+		}
+		elapsed, err := junitxml.ParseTime(suite.Time)
+		if err != nil {
+			return nil, errors.Wrapf(err, "testsuite %s", suite.Name)
+		}
+		pkgEvent := testjson.TestEvent{
+			Action:  pkgAction,
+			Package: suite.Name,
+			Elapsed: elapsed.Seconds(),
+		}
+		if err := encoder.Encode(pkgEvent); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// junitTestCaseEvents returns the run, output, and result events of a
+// testcase. An error element is reported as a failure.
+func junitTestCaseEvents(pkg string, tc junitxml.JUnitTestCase) ([]testjson.TestEvent, error) {
+	elapsed, err := junitxml.ParseTime(tc.Time)
+	if err != nil {
+		return nil, errors.Wrapf(err, "testcase %s", tc.Name)
+	}
+	newEvent := func(action testjson.Action, output string) testjson.TestEvent {
+		return testjson.TestEvent{Action: action, Package: pkg, Test: tc.Name, Output: output}
+	}
+
+	events := []testjson.TestEvent{newEvent(testjson.ActionRun, "")}
+	result := newEvent(testjson.ActionPass, "")
+	switch {
+	case tc.Failure != nil:
+		result.Action = testjson.ActionFail
+		events = append(events, junitOutputEvents(newEvent, *tc.Failure)...)
+	case tc.Error != nil:
+		result.Action = testjson.ActionFail
+		events = append(events, junitOutputEvents(newEvent, *tc.Error)...)
+	case tc.SkipMessage != nil:
+		result.Action = testjson.ActionSkip
+		events = append(events, junitOutputEvents(newEvent, junitxml.JUnitFailure{
+			Message: tc.SkipMessage.Message,
+		})...)
+	}
+	result.Elapsed = elapsed.Seconds()
+	return append(events, result), nil
+}
+
+// junitOutputEvents returns an output event for each line of the contents of
+// the failure, or for the message when there are no contents.
+func junitOutputEvents(
+	newEvent func(testjson.Action, string) testjson.TestEvent,
+	failure junitxml.JUnitFailure,
+) []testjson.TestEvent {
+	output := failure.Contents
+	if strings.TrimSpace(output) == "" {
+		output = failure.Message
+	}
+	var events []testjson.TestEvent
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		events = append(events, newEvent(testjson.ActionOutput, line))
+	}
+	return events
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// readKnownFlakyFile returns the patterns from the --known-flaky-file. Each
+// line of the file is a regular expression. Empty lines, and lines which
+// start with #, are ignored.
+func readKnownFlakyFile(filename string) ([]*regexp.Regexp, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open --known-flaky-file")
+	}
+	defer file.Close() // nolint: errcheck
+
+	var patterns []*regexp.Regexp
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern in --known-flaky-file %s", filename)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, errors.Wrapf(scanner.Err(), "failed to read %s", filename)
+}
+
+func validateKnownFlakyFile(opts *Options) error {
+	switch {
+	case opts.KnownFlakyFile == "" && opts.KnownFlakyNonfatal:
+		return errors.New("--known-flaky-nonfatal requires --known-flaky-file")
+	case opts.KnownFlakyFile == "":
+		return nil
+	}
+	_, err := readKnownFlakyFile(opts.KnownFlakyFile)
+	return err
+}
+
+// knownFlaky returns the failed test cases which are known to be flaky, and
+// true if every failure of the run is a known flaky test. A test is known to
+// be flaky when a pattern matches the package path and test name, joined by a
+// space, or when all of its failed subtests are known to be flaky.
+func knownFlaky(
+	exec *testjson.Execution,
+	patterns []*regexp.Regexp,
+) ([]testjson.TestCase, bool) {
+	failed := exec.Failed()
+	matched := make(map[testjson.TestCase]bool)
+	for _, tc := range failed {
+		for _, pattern := range patterns {
+			if pattern.MatchString(tc.Package + " " + tc.Test) {
+				matched[tc] = true
+				break
+			}
+		}
+	}
+	var flaky []testjson.TestCase
+	for _, tc := range failed {
+		if matched[tc] || tc.Test != "" && subtestsKnownFlaky(failed, matched, tc) {
+			flaky = append(flaky, tc)
+		}
+	}
+	return flaky, len(flaky) == len(failed) && len(exec.BuildFailures()) == 0
+}
+
+// subtestsKnownFlaky returns true if tc has failed subtests, and all of them
+// are known to be flaky.
+func subtestsKnownFlaky(
+	failed []testjson.TestCase,
+	matched map[testjson.TestCase]bool,
+	tc testjson.TestCase,
+) bool {
+	var count int
+	for _, sub := range failed {
+		if sub.Package != tc.Package || !strings.HasPrefix(sub.Test, tc.Test+"/") {
+			continue
+		}
+		if !matched[sub] {
+			return false
+		}
+		count++
+	}
+	return count > 0
+}
+
+// knownFlakyExitErr prints the failed tests which are known to be flaky. With
+// --known-flaky-nonfatal nil is returned when every failure of the run is a
+// known flaky test, and go test exited with 1 because tests failed. A run
+// which was stopped early, for example by a signal or --max-failures, keeps
+// its exit code because the tests which did not run may have failed.
+// Otherwise exitErr is returned.
+func knownFlakyExitErr(
+	opts *Options,
+	out io.Writer,
+	exec *testjson.Execution,
+	exitErr error,
+) error {
+	if opts.KnownFlakyFile == "" {
+		return exitErr
+	}
+	patterns, err := readKnownFlakyFile(opts.KnownFlakyFile)
+	if err != nil {
+		log.WithError(err).Warn("failed to read known flaky tests")
+		return exitErr
+	}
+	flaky, onlyFlaky := knownFlaky(exec, patterns)
+	if len(flaky) == 0 {
+		return exitErr
+	}
+	fmt.Fprintln(out, color.YellowString("\n=== Known flaky failures"))
+	for _, tc := range flaky {
+		fmt.Fprintln(out, strings.TrimSpace(testjson.RelativePackagePath(tc.Package)+" "+tc.Test))
+	}
+	if opts.KnownFlakyNonfatal && onlyFlaky && len(exec.Errors()) == 0 && isTestFailure(exitErr) {
+		fmt.Fprintln(out, "All failures are known flaky tests (--known-flaky-nonfatal)")
+		return nil
+	}
+	return exitErr
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestRun_KnownFlakyNonfatal(t *testing.T) {
+	defer patchNoColor(true)()
+	dir, err := ioutil.TempDir("", "gotestsum-known-flaky")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "flaky.txt")
+	patterns := "# known flaky tests\n\nexample.com/pkg TestFlaky/case$\n"
+	assert.NilError(t, ioutil.WriteFile(filename, []byte(patterns), 0644))
+
+	script := `echo '{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky/case"}'
+echo '{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}'
+echo '{"Action":"pass","Package":"example.com/pkg","Test":"TestOther"}'
+echo '{"Action":"fail","Package":"example.com/pkg"}'
+exit 1`
+	opts := Options{
+		Format:             "short",
+		RawCommandShell:    true,
+		Args:               []string{script},
+		KnownFlakyFile:     filename,
+		KnownFlakyNonfatal: true,
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	expected := `
+=== Known flaky failures
+example.com/pkg TestFlaky/case
+example.com/pkg TestFlaky
+All failures are known flaky tests (--known-flaky-nonfatal)
+`
+	assert.Assert(t, cmp.Contains(out.String(), expected), out.String())
+
+	// a failure which is not known to be flaky still fails the run
+	opts.Args = []string{`echo '{"Action":"fail","Package":"example.com/pkg","Test":"TestOther"}'
+echo '{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky/case"}'
+exit 1`}
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "")
+}
+
+func TestKnownFlakyExitErr_StoppedRun(t *testing.T) {
+	defer patchNoColor(true)()
+	dir, err := ioutil.TempDir("", "gotestsum-known-flaky")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "flaky.txt")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("TestFlaky\n"), 0644))
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(`{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}`),
+		Stderr:  strings.NewReader(""),
+		Handler: noopHandler{},
+	})
+	assert.NilError(t, err)
+	opts := &Options{KnownFlakyFile: filename, KnownFlakyNonfatal: true}
+
+	interrupt := &interruptHandler{signal: os.Interrupt}
+	limit := newFailureLimit(noopHandler{}, 0, false, func() {})
+	stopErr := stoppedExitErr(opts, limit, interrupt)
+	err = knownFlakyExitErr(opts, new(bytes.Buffer), exec, stopErr)
+	assert.Equal(t, ExitCodeWithDefault(err), 130)
+
+	limit = newFailureLimit(noopHandler{}, 1, false, func() {})
+	limit.count = 1
+	stopErr = stoppedExitErr(opts, limit, &interruptHandler{})
+	err = knownFlakyExitErr(opts, new(bytes.Buffer), exec, stopErr)
+	assert.Error(t, err, "reached --max-failures")
+
+	err = knownFlakyExitErr(opts, new(bytes.Buffer), exec, &exitError{code: 1})
+	assert.NilError(t, err)
+}
+
+func TestValidateKnownFlakyFile(t *testing.T) {
+	err := validateKnownFlakyFile(&Options{KnownFlakyNonfatal: true})
+	assert.ErrorContains(t, err, "--known-flaky-nonfatal requires --known-flaky-file")
+
+	dir, err := ioutil.TempDir("", "gotestsum-known-flaky")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	filename := filepath.Join(dir, "flaky.txt")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("TestFlaky(\n"), 0644))
+	err = validateKnownFlakyFile(&Options{KnownFlakyFile: filename})
+	assert.ErrorContains(t, err, "invalid pattern in --known-flaky-file")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"io"
+	"os"
+)
+
+type syncFile interface {
+	io.WriteCloser
+	Sync() error
+}
+
+// syncWriter is a writer which commits each write to disk, with --line-buffered,
+// so that the output is visible to the readers of the file as soon as each
+// event is written, even on file systems which delay the writes.
+type syncWriter struct {
+	syncFile
+}
+
+func (w syncWriter) Write(p []byte) (int, error) {
+	n, err := w.syncFile.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, w.Sync()
+}
+
+// syncOutput returns a syncWriter for out when --line-buffered is set and out
+// is a regular file. Other writers, like a terminal or a pipe, are returned
+// unchanged, because they are not buffered by the OS.
+func syncOutput(opts *Options, out io.Writer) io.Writer {
+	file, ok := out.(*os.File)
+	if !opts.LineBuffered || !ok {
+		return out
+	}
+	if info, err := file.Stat(); err != nil || !info.Mode().IsRegular() {
+		return out
+	}
+	return syncWriter{syncFile: file}
+}
+
+// syncJSONFile returns a syncWriter for the --jsonfile when --line-buffered is
+// set.
+func syncJSONFile(opts *Options, file syncFile) io.WriteCloser {
+	if !opts.LineBuffered {
+		return file
+	}
+	return syncWriter{syncFile: file}
+}
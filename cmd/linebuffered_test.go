@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSyncOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-line-buffered")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	file, err := os.Create(filepath.Join(dir, "output.txt"))
+	assert.NilError(t, err)
+	defer file.Close() // nolint: errcheck
+
+	opts := &Options{}
+	assert.Equal(t, syncOutput(opts, file), io.Writer(file))
+
+	opts.LineBuffered = true
+	out := syncOutput(opts, file)
+	assert.Equal(t, out, io.Writer(syncWriter{syncFile: file}))
+	_, err = out.Write([]byte("ok\n"))
+	assert.NilError(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.Equal(t, syncOutput(opts, buf), io.Writer(buf))
+
+	reader, writer, err := os.Pipe()
+	assert.NilError(t, err)
+	defer reader.Close() // nolint: errcheck
+	defer writer.Close() // nolint: errcheck
+	assert.Equal(t, syncOutput(opts, writer), io.Writer(writer))
+}
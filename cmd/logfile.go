@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// logFormatter returns the formatter of the gotestsum logs for --log-format.
+func logFormatter(format string) log.Formatter {
+	if format == "json" {
+		return &log.JSONFormatter{}
+	}
+	return &log.TextFormatter{}
+}
+
+func validateLogFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		return nil
+	}
+	return errors.Errorf("invalid --log-format value %s, must be one of: text, json", format)
+}
+
+// setupLogFile sends the gotestsum logs to --log-file, so that they are not
+// mixed with the test output on stderr. The returned function closes the file,
+// and sends the logs to stderr again.
+func setupLogFile(opts *Options) (func(), error) {
+	if opts.LogFile == "" {
+		return func() {}, nil
+	}
+	file, err := os.OpenFile(opts.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open log file")
+	}
+	log.SetOutput(file)
+	return func() {
+		log.SetOutput(os.Stderr)
+		file.Close() // nolint: errcheck
+	}, nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_LogFile(t *testing.T) {
+	defer log.SetLevel(log.GetLevel())
+	defer log.SetFormatter(&log.TextFormatter{})
+	dir, err := ioutil.TempDir("", "gotestsum-log")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:     "short",
+		RawCommand: true,
+		Args:       []string{"cat", "testdata/skipped.json"},
+		Debug:      true,
+		LogFile:    filepath.Join(dir, "gotestsum.log"),
+		LogFormat:  "json",
+	}
+	assert.NilError(t, Run(context.Background(), opts, new(bytes.Buffer)))
+
+	raw, err := ioutil.ReadFile(opts.LogFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), `"level":"debug"`))
+	assert.Assert(t, cmp.Contains(string(raw), `"msg":"exec: [cat testdata/skipped.json]"`))
+}
+
+func TestValidateLogFormat(t *testing.T) {
+	assert.NilError(t, validateLogFormat("json"))
+	assert.ErrorContains(t, validateLogFormat("xml"), "invalid --log-format value xml")
+}
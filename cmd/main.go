@@ -0,0 +1,1239 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Main parses the command line flags in args and runs gotestsum. name is the
+// name of the program, which is used in the usage text. When go test fails,
+// or the run should exit with a non-zero status, the returned error has an
+// exit code which can be retrieved with ExitCodeWithDefault.
+func Main(name string, args []string) error {
+	flags, opts := setupFlags(name)
+	if err := flags.Parse(args); err != nil {
+		// the flagset has already printed the error
+		return &exitError{code: 1, reason: err.Error()}
+	}
+	if opts.Version {
+		printVersion(os.Stdout, filepath.Base(name))
+		return nil
+	}
+	if err := loadOptions(flags, opts, configFilename); err != nil {
+		return err
+	}
+	return Run(context.Background(), *opts, os.Stdout)
+}
+
+// loadOptions sets the options from the config file, the go test args, and
+// GOTESTSUM_ARGS, after the flags are parsed.
+func loadOptions(flags *pflag.FlagSet, opts *Options, configFile string) error {
+	// --no-color=false forces color on, even when the output is not a
+	// terminal. This is checked before the config file is loaded, because
+	// setting a flag from the config file also marks it as changed.
+	if flags.Changed("no-color") && !opts.NoColor {
+		opts.ForceColor = true
+	}
+	if err := loadConfigFile(flags, configFile); err != nil {
+		return err
+	}
+	opts.Args = flags.Args()
+	defaultArgs, err := splitArgs(os.Getenv("GOTESTSUM_ARGS"))
+	if err != nil {
+		return errors.Wrap(err, "invalid GOTESTSUM_ARGS")
+	}
+	opts.DefaultArgs = defaultArgs
+	return nil
+}
+
+func setupFlags(name string) (*pflag.FlagSet, *Options) {
+	opts := &Options{}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage:
+    %s [flags] [--] [go test flags]
+
+Flags:
+`, name)
+		flags.PrintDefaults()
+		fmt.Fprint(os.Stderr, `
+Formats:
+    dots              print a character for each test
+    pkgname           print a line with the test counts for each package
+    short             print a line for each package
+    short-verbose     print a line for each test and package
+    short-with-failures
+                      print a line for each package, and the failed tests
+    standard-quiet    default go test format
+    standard-verbose  default go test -v format
+    standard-json     the go test -json output
+    tap               print the Test Anything Protocol format
+`)
+	}
+	flags.BoolVar(&opts.Debug, "debug", false, "enabled debug")
+	flags.StringVar(&opts.LogFile, "log-file", "",
+		"append the gotestsum logs to file, instead of writing them to stderr")
+	flags.StringVar(&opts.LogFormat, "log-format", "text",
+		"format of the gotestsum logs: text, json")
+	flags.BoolVar(&opts.Version, "version", false, "print the version and exit")
+	flags.StringVar(&opts.Format, "format",
+		lookEnvWithDefault("GOTESTSUM_FORMAT", "short"),
+		"print format of test input")
+	flags.IntVar(&opts.MaxLineWidth, "max-line-width", 0,
+		"truncate package and test names in the short formats to fit this width, "+
+			"-1 for no limit (default the terminal width)")
+	flags.StringVar(&opts.FormatPackagePath, "format-package-path", "relative",
+		"print package paths as: relative (to the current directory), module (relative to go.mod), "+
+			"module-relative (relative to the module from go list -m), full")
+	flags.StringVar(&opts.DotsPass, "dots-pass", "·",
+		"character printed by the dots format for a test which passed")
+	flags.StringVar(&opts.DotsFail, "dots-fail", "✖",
+		"character printed by the dots format for a test which failed")
+	flags.StringVar(&opts.DotsSkip, "dots-skip", "↷",
+		"character printed by the dots format for a test which was skipped")
+	flags.BoolVar(&opts.DotsNoColor, "dots-no-color", false,
+		"print the characters of the dots format without color")
+	flags.BoolVar(&opts.HidePassed, "hide-passed", false,
+		"hide the output of tests which pass")
+	flags.BoolVar(&opts.OnlyFailures, "only-failures", false,
+		"print only the complete output of failed tests, in place of the --format")
+	flags.StringVar(&opts.FocusPackages, "focus-packages", "",
+		"only print the output of packages matching this regex, other packages print a count")
+	flags.BoolVar(&opts.Progress, "progress", false,
+		"print a status line with the number of passed and failed tests, with the dots format")
+	flags.StringVar(&opts.GroupOutputBy, "group-output-by", "",
+		"group the output of each package together: package")
+	flags.BoolVar(&opts.CollapsePassingPackages, "collapse-passing-packages", false,
+		"print only the number of packages which passed, and the output of other packages")
+	flags.Var((*packagesValue)(&opts.Packages), "packages",
+		"space separated list of packages to test, instead of TEST_DIRECTORY or ./...")
+	flags.StringVar(&opts.PackagesFromFile, "packages-from-file", "",
+		"file with a package to test on each line, added to the --packages")
+	flags.BoolVar(&opts.RawCommand, "raw-command", false,
+		"don't prepend 'go test -json' to the 'go test' command")
+	flags.BoolVar(&opts.RawCommandShell, "raw-command-shell", false,
+		"like --raw-command, but run the command as a single string with 'sh -c'")
+	flags.BoolVar(&opts.RawCommandJSONStderr, "raw-command-json-stderr", false,
+		"read the test2json events of the --raw-command from stderr instead of stdout")
+	flags.StringVar(&opts.GoBinary, "go-binary",
+		lookEnvWithDefault("GOTESTSUM_GOBINARY", "go"),
+		"path to the go executable used to run 'go test'")
+	flags.StringVar(&opts.GoTestSubcommand, "go-test-subcommand", "test",
+		"subcommand used in place of 'test', the -json flag is still added")
+	flags.BoolVar(&opts.DryRun, "dry-run", false,
+		"print the go test command, and exit without running it")
+	flags.StringVar(&opts.RawFromFile, "raw-from-file", "",
+		"read test2json output from a file, or - for stdin, instead of running go test")
+	flags.StringVar(&opts.JUnitFromFile, "junit-from-file", "",
+		"read the test results from a JUnit XML file instead of running go test")
+	flags.BoolVar(&opts.MergeJSON, "merge-json", false,
+		"combine the test2json files passed as arguments, instead of running go test")
+	flags.StringVar(&opts.JSONFile, "jsonfile",
+		lookEnvWithDefault("GOTESTSUM_JSONFILE", ""),
+		"write all TestEvents to file, or - to write them to stdout with the formatted output")
+	flags.Var((*byteSizeValue)(&opts.JSONFileMaxSize), "jsonfile-max-size",
+		"rotate the --jsonfile to file.1 when it reaches this size, ex: 100MB")
+	flags.BoolVar(&opts.OutputToStderr, "output-to-stderr", false,
+		"write the formatted test output to stderr, the summary is still written to stdout")
+	flags.StringVar(&opts.OutputFile, "output-file", "",
+		"also write the formatted output and the summary to file")
+	flags.StringVar(&opts.StdoutFile, "stdout-file", "",
+		"write the stdout of go test to file, unchanged, before it is parsed")
+	flags.StringVar(&opts.StderrFile, "stderr-file", "",
+		"write the stderr of go test to file, unchanged, before it is parsed")
+	flags.StringVar(&opts.JUnitFile, "junitfile",
+		lookEnvWithDefault("GOTESTSUM_JUNITFILE", ""),
+		"write a JUnit XML file, or - to write it to stdout after the summary")
+	flags.BoolVar(&opts.Append, "append", false,
+		"append to the --jsonfile and --junitfile instead of replacing them")
+	flags.Var(&opts.JUnitTestSuiteNameFormat, "junitfile-testsuite-name",
+		"format the testsuite name field as: full, relative, short (default full)")
+	flags.Var(&opts.JUnitTestCaseClassnameFormat, "junitfile-testcase-classname",
+		"format the testcase classname field as: full, relative, short (default short)")
+	flags.StringVar(&opts.JUnitProjectName, "junitfile-project-name", "",
+		"name of the project used as the name of the testsuites element")
+	flags.StringArrayVar(&opts.JUnitProperties, "junitfile-property", nil,
+		"add a KEY=VALUE property to each testsuite in the --junitfile, may be repeated")
+	flags.BoolVar(&opts.JUnitHideEmptyPackages, "junitfile-hide-empty-packages", false,
+		"omit packages with no tests from the JUnit XML file")
+	flags.BoolVar(&opts.JUnitFileOnStartupError, "junitfile-on-startup-error", false,
+		"write a --junitfile with an error when the go binary fails to start, "+
+			"not when go test exits with an error for invalid flags")
+	flags.StringVar(&opts.JSONSummaryFile, "json-summary",
+		lookEnvWithDefault("GOTESTSUM_JSON_SUMMARY", ""),
+		"write a JSON summary of the test run to file")
+	flags.StringVar(&opts.MarkdownSummaryFile, "markdown-summary", "",
+		"write a Markdown summary of the test run to file, for a pull request comment")
+	flags.StringVar(&opts.TraceFile, "trace-file", "",
+		"write the tests as Chrome trace events to file, for chrome://tracing or Perfetto")
+	flags.BoolVar(&opts.NoColor, "no-color", noColorFromEnv(),
+		"disable color output, defaults to true when NO_COLOR or GOTESTSUM_NO_COLOR is set")
+	flags.BoolVar(&opts.ForceColor, "force-color", forceColorFromEnv(),
+		"print color output even when stdout is not a terminal, defaults to true when FORCE_COLOR is set")
+	flags.BoolVar(&opts.HighlightDiffs, "highlight-diffs", false,
+		"highlight the expected and actual values in testify assertion failures")
+	flags.StringArrayVar(&opts.TrimPathPrefix, "trim-path-prefix", nil,
+		"remove this prefix from the file paths in the output, may be repeated")
+	flags.Var((*noSummaryValue)(&opts.NoSummary), "no-summary",
+		"do not print summary of: failed, skipped, errors, race, panic, timeout, all, "+
+			"or the test output")
+	flags.StringSliceVar(&opts.Summary, "summary", nil,
+		"print only these sections of the summary: "+
+			"failed, skipped, errors, race, panic, timeout")
+	flags.BoolVar(&opts.DedupeFailures, "dedupe-failures", false,
+		"print failed tests with the same output as a single entry in the summary")
+	flags.BoolVar(&opts.GroupSubtests, "group-subtests", false,
+		"print failed subtests under their top level test in the summary")
+	flags.StringArrayVar(&opts.Env, "env", nil,
+		"set an environment variable, in the form KEY=VALUE, for go test (may be repeated)")
+	flags.BoolVar(&opts.IgnoreNonTestPackages, "ignore-non-test-packages", false,
+		"do not print or count packages which have no test files")
+	flags.IntVar(&opts.MaxTestOutputBytes, "max-test-output-bytes", 0,
+		"keep only the last N bytes of the output of each test for the summary (0 is unlimited)")
+	flags.BoolVar(&opts.ShowSkipReasons, "show-skip-reasons", false,
+		"print a line with the skip message of each skipped test in the summary")
+	flags.BoolVar(&opts.SortFailures, "sort-failures", false,
+		"sort the failed tests in the summary by package and test name")
+	flags.BoolVar(&opts.ShowFailuresLast, "show-failures-last", false,
+		"print the complete output of all failed tests after the summary")
+	flags.Var((*byteSizeValue)(&opts.ShowFailuresLastLimit), "show-failures-last-limit",
+		"the maximum size of the output printed by --show-failures-last, ex: 10MB (default 1MB)")
+	flags.BoolVar(&opts.Coverage, "coverage", false,
+		"print the coverage of each package in the summary, requires go test -cover")
+	flags.BoolVar(&opts.HighlightFirstFailure, "highlight-first-failure", false,
+		"print the complete output of the first test to fail after the summary")
+	flags.BoolVar(&opts.CompactSummary, "compact-summary", false,
+		"print a DONE line with every count, in the same format for every run")
+	flags.StringVar(&opts.SummaryPosition, "summary-position", "bottom",
+		"print the DONE line at the top or bottom of the summary: top, bottom")
+	flags.BoolVar(&opts.SummaryHeader, "summary-header", false,
+		"print the go version, hostname, and go test command before the test output")
+	flags.BoolVar(&opts.LineBuffered, "line-buffered", false,
+		"commit the output to disk after each event, when the output is written to a file")
+	flags.StringVar(&opts.GitHubActions, "github-actions", "",
+		"print GitHub Actions annotations for failed tests: auto (only in GitHub Actions), always")
+	flags.Lookup("github-actions").NoOptDefVal = "auto"
+	flags.IntVar(&opts.ExitCodeOnFailure, "exit-code-on-failure", 0,
+		"exit with this status code, instead of the go test status code, when the run fails")
+	flags.IntVar(&opts.ExitCodeOnInternalError, "exit-code-on-internal-error", 0,
+		"exit with this status code, instead of 3, when gotestsum fails to run the tests")
+	flags.BoolVar(&opts.QuietOnSuccess, "quiet-on-success", false,
+		"print nothing when the run succeeds, and all the output when it fails")
+	flags.BoolVar(&opts.NoTestsFail, "no-tests-fail", false,
+		"exit with a non-zero status code when no tests were run")
+	flags.BoolVar(&opts.SkippedFail, "skipped-fail", false,
+		"exit with a non-zero status code when any tests were skipped")
+	flags.StringVar(&opts.KnownFlakyFile, "known-flaky-file", "",
+		"file with a regular expression on each line, matching the package and name of flaky tests")
+	flags.BoolVar(&opts.KnownFlakyNonfatal, "known-flaky-nonfatal", false,
+		"exit with 0 when all the failed tests are in the --known-flaky-file")
+	flags.StringVar(&opts.FailOnOutput, "fail-on-output", "",
+		"exit with a non-zero status code when the output of a test matches this regular expression")
+	flags.IntVar(&opts.MaxFailures, "max-failures", 0,
+		"stop the test run after this number of test failures, failed tests are not rerun")
+	flags.BoolVar(&opts.FailFast, "fail-fast", false,
+		"run go test with -failfast, and stop the test run after the first test failure")
+	flags.BoolVar(&opts.PanicsFailFast, "panics-fail-fast", false,
+		"stop the test run when a test panics, failed tests are not rerun")
+	flags.IntVar(&opts.Slowest, "slowest", 0,
+		"print the N slowest tests after the summary")
+	flags.DurationVar(&opts.SlowPackageThreshold, "slow-package-threshold", 0,
+		"print a warning for packages with a total test time greater than this duration")
+	flags.BoolVar(&opts.ShowTestTimes, "show-test-times", false,
+		"highlight the elapsed time of slow tests in the standard-verbose and short-verbose formats")
+	flags.DurationVar(&opts.ShowTestTimesThreshold, "show-test-times-threshold", time.Second,
+		"the elapsed time of a test which is highlighted by --show-test-times")
+	flags.BoolVar(&opts.SlowPackageFail, "slow-package-fail", false,
+		"exit with a non-zero status code when a package exceeds --slow-package-threshold")
+	flags.BoolVar(&opts.TimingHistogram, "timing-histogram", false,
+		"print a histogram of the elapsed time of the tests after the summary")
+	flags.Var((*durationsValue)(&opts.TimingHistogramBounds), "timing-histogram-bounds",
+		"comma separated upper bounds of the histogram buckets (default 10ms,100ms,1s)")
+	flags.BoolVar(&opts.Watch, "watch", false,
+		"watch go files, and run tests when a file is modified")
+	flags.IntVar(&opts.UntilFail, "until-fail", 0,
+		"run the tests until a run fails, or --until-fail=N to stop after N passing runs")
+	flags.Lookup("until-fail").NoOptDefVal = "-1"
+	flags.Var(&opts.PostRunHookCmd, "post-run-command",
+		"command to run after the tests have completed")
+	flags.IntVar(&opts.RerunFailsMaxAttempts, "rerun-fails", 0,
+		"rerun failed tests until they pass, or the number of reruns reaches this maximum")
+	flags.StringVar(&opts.RerunFailsPackages, "rerun-fails-packages", "",
+		"only rerun failed tests when all the failures are in packages matching this regex")
+	flags.StringVar(&opts.RerunFailsReportFile, "rerun-fails-report", "",
+		"write the tests which failed and then passed when they were rerun to this file")
+	flags.IntVar(&opts.RerunFailsMaxFailures, "rerun-fails-max-failures", 0,
+		"do not rerun any tests when more than this number of tests failed (0 is no limit). "+
+			"Nothing is rerun when the run was stopped by --max-failures")
+	return flags, opts
+}
+
+// noColorFromEnv returns true when color is disabled by the environment.
+// GOTESTSUM_NO_COLOR is a boolean, and takes precedence over the NO_COLOR
+// convention (https://no-color.org), which disables color when it is set to
+// any non-empty value.
+func noColorFromEnv() bool {
+	if value, ok := os.LookupEnv("GOTESTSUM_NO_COLOR"); ok {
+		noColor, err := strconv.ParseBool(value)
+		return err != nil || noColor
+	}
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// forceColorFromEnv returns true when the FORCE_COLOR environment variable is
+// set to a value other than 0 or false.
+func forceColorFromEnv() bool {
+	value, ok := os.LookupEnv("FORCE_COLOR")
+	return ok && value != "0" && value != "false"
+}
+
+// maxLineWidth returns the --max-line-width, or the width of the terminal when
+// the flag is not set. A negative value disables the truncation.
+func maxLineWidth(opts *Options) int {
+	switch {
+	case opts.MaxLineWidth < 0:
+		return 0
+	case opts.MaxLineWidth == 0:
+		return defaultLineWidth()
+	}
+	return opts.MaxLineWidth
+}
+
+// defaultLineWidth returns the width of the terminal when stdout is a
+// terminal, otherwise 0.
+func defaultLineWidth() int {
+	if !isTerminal(os.Stdout) {
+		return 0
+	}
+	if width := terminalWidth(os.Stdout); width > 0 {
+		return width
+	}
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	return width
+}
+
+func lookEnvWithDefault(key, defValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defValue
+}
+
+// Options configures a run of gotestsum. The fields correspond to the command
+// line flags of the same name.
+type Options struct {
+	// Args are the arguments passed to go test, or the full command to run
+	// when RawCommand or RawCommandShell is true.
+	Args                         []string
+	Packages                     []string
+	PackagesFromFile             string
+	Format                       string
+	MaxLineWidth                 int
+	FormatPackagePath            string
+	DotsPass                     string
+	DotsFail                     string
+	DotsSkip                     string
+	DotsNoColor                  bool
+	GroupOutputBy                string
+	CollapsePassingPackages      bool
+	Progress                     bool
+	FocusPackages                string
+	HidePassed                   bool
+	OnlyFailures                 bool
+	Debug                        bool
+	LogFile                      string
+	LogFormat                    string
+	Version                      bool
+	RawCommand                   bool
+	RawCommandJSONStderr         bool
+	RawCommandShell              bool
+	GoBinary                     string
+	GoTestSubcommand             string
+	DryRun                       bool
+	Env                          []string
+	RawFromFile                  string
+	JUnitFromFile                string
+	MergeJSON                    bool
+	JSONFile                     string
+	JSONFileMaxSize              int64
+	OutputFile                   string
+	StdoutFile                   string
+	StderrFile                   string
+	OutputToStderr               bool
+	JUnitFile                    string
+	JUnitTestSuiteNameFormat     JUnitFieldFormatValue
+	JUnitTestCaseClassnameFormat JUnitFieldFormatValue
+	JUnitProjectName             string
+	JUnitHideEmptyPackages       bool
+	JUnitFileOnStartupError      bool
+	JUnitProperties              []string
+	Append                       bool
+	JSONSummaryFile              string
+	MarkdownSummaryFile          string
+	TraceFile                    string
+	NoColor                      bool
+	ForceColor                   bool
+	HighlightDiffs               bool
+	TrimPathPrefix               []string
+	NoSummary                    []string
+	Summary                      []string
+	CompactSummary               bool
+	SummaryPosition              string
+	SummaryHeader                bool
+	LineBuffered                 bool
+	DedupeFailures               bool
+	GroupSubtests                bool
+	ShowSkipReasons              bool
+	HighlightFirstFailure        bool
+	Coverage                     bool
+	SortFailures                 bool
+	MaxTestOutputBytes           int
+	IgnoreNonTestPackages        bool
+	ShowFailuresLast             bool
+	ShowFailuresLastLimit        int64
+	RerunFailsMaxAttempts        int
+	RerunFailsPackages           string
+	RerunFailsReportFile         string
+	RerunFailsMaxFailures        int
+	Slowest                      int
+	SlowPackageThreshold         time.Duration
+	SlowPackageFail              bool
+	ShowTestTimes                bool
+	ShowTestTimesThreshold       time.Duration
+	TimingHistogram              bool
+	TimingHistogramBounds        []time.Duration
+	MaxFailures                  int
+	PanicsFailFast               bool
+	FailFast                     bool
+	PostRunHookCmd               CommandValue
+	Watch                        bool
+	UntilFail                    int
+	NoTestsFail                  bool
+	QuietOnSuccess               bool
+	GitHubActions                string
+	ExitCodeOnFailure            int
+	ExitCodeOnInternalError      int
+	SkippedFail                  bool
+	KnownFlakyFile               string
+	KnownFlakyNonfatal           bool
+	FailOnOutput                 string
+	// Observers are called with the Execution once all the tests have run.
+	// They have no command line flag.
+	Observers []ExecutionObserver
+	// EventHandlers are called with each event as it is read, after the event
+	// is printed, and with each line of stderr. They have no command line
+	// flag.
+	EventHandlers []testjson.EventHandler
+	// HasJSONArg returns true if the go test args already enable JSON output,
+	// for flags which are not recognized by default, like the flags of a
+	// build system which runs the test binary. The -json flag is not added to
+	// the go test command when it returns true. It has no command line flag.
+	HasJSONArg func(args []string) bool
+	// DefaultArgs are added to the go test command before Args. Main sets
+	// them from the GOTESTSUM_ARGS environment variable.
+	DefaultArgs []string
+
+	// stderr receives the stderr of go test, and the formatted output with
+	// --output-to-stderr. It is set by runQuietOnSuccess, otherwise
+	// os.Stderr is used.
+	stderr io.Writer
+}
+
+// stderrOutput returns the writer for the output which is written to stderr.
+func stderrOutput(opts *Options) io.Writer {
+	if opts.stderr == nil {
+		return os.Stderr
+	}
+	return opts.stderr
+}
+
+// setupLogging configures the logger and the color output from opts. The
+// returned function restores the previous configuration.
+func setupLogging(opts *Options) func() {
+	level, formatter, noColor := log.GetLevel(), log.StandardLogger().Formatter, color.NoColor
+	restore := func() {
+		log.SetLevel(level)
+		log.SetFormatter(formatter)
+		color.NoColor = noColor
+	}
+	if opts.Debug {
+		log.SetLevel(log.DebugLevel)
+	}
+	log.SetFormatter(logFormatter(opts.LogFormat))
+	switch {
+	case opts.NoColor:
+		color.NoColor = true
+	case opts.ForceColor:
+		color.NoColor = false
+	case isDumbTerminal():
+		color.NoColor = true
+	}
+	return restore
+}
+
+// isDumbTerminal returns true when TERM is dumb, or when TERM is not set and
+// stdout is not a terminal, because the output may not support color codes.
+func isDumbTerminal() bool {
+	term, ok := os.LookupEnv("TERM")
+	return term == "dumb" || !ok && !isTerminal(os.Stdout)
+}
+
+// Run runs go test, or reads the test2json output from opts.RawFromFile, and
+// writes the formatted output and summary to out. If go test fails, or the
+// result of the run should cause a non-zero exit, an error with an exit code
+// is returned. The exit code, and the exit code of other errors, may be
+// changed by opts.ExitCodeOnFailure and opts.ExitCodeOnInternalError.
+func Run(ctx context.Context, opts Options, out io.Writer) error {
+	defer setupLogging(&opts)()
+	closeLogFile, err := setupLogFile(&opts)
+	if err != nil {
+		return remapExitCode(&opts, err)
+	}
+	defer closeLogFile()
+	if err := addPackagesFromFile(&opts); err != nil {
+		return remapExitCode(&opts, err)
+	}
+	out = syncOutput(&opts, out)
+	if opts.DryRun {
+		return remapExitCode(&opts, runDryRun(&opts, out))
+	}
+	if opts.QuietOnSuccess {
+		return remapExitCode(&opts, runQuietOnSuccess(ctx, &opts, out))
+	}
+	return remapExitCode(&opts, run(ctx, &opts, out))
+}
+
+// runQuietOnSuccess buffers all the output of the run, and only writes it to
+// out when the run fails. The output to stderr is buffered separately, and is
+// written to stderr when the run fails.
+func runQuietOnSuccess(ctx context.Context, opts *Options, out io.Writer) error {
+	buf, errBuf := new(bytes.Buffer), new(bytes.Buffer)
+	stderr := stderrOutput(opts)
+	opts.stderr = errBuf
+	err := run(ctx, opts, buf)
+	opts.stderr = stderr
+	if err == nil {
+		return nil
+	}
+	if _, werr := errBuf.WriteTo(stderr); werr != nil {
+		log.WithError(werr).Warn("failed to write test output")
+	}
+	if _, werr := buf.WriteTo(out); werr != nil {
+		log.WithError(werr).Warn("failed to write test output")
+	}
+	return err
+}
+
+func run(ctx context.Context, opts *Options, out io.Writer) error {
+	if err := validateOpts(opts); err != nil {
+		return err
+	}
+	restorePrefix, err := setPackagePathPrefix(opts)
+	if err != nil {
+		return err
+	}
+	defer restorePrefix()
+	screen := terminalOutput(out)
+	if opts.OutputFile != "" {
+		file, err := os.Create(opts.OutputFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to create output file")
+		}
+		defer file.Close() // nolint: errcheck
+		out = io.MultiWriter(out, syncOutput(opts, file))
+	}
+	switch {
+	case noPackagesToTest(opts):
+		return runWithoutPackages(opts, out)
+	case opts.Watch:
+		return runWatcher(ctx, opts, out, screen)
+	case opts.RawFromFile != "", opts.JUnitFromFile != "", opts.MergeJSON:
+		return runFromFile(opts, out)
+	}
+	if opts.SummaryHeader {
+		printSummaryHeader(opts, out)
+	}
+	if opts.UntilFail != 0 {
+		return runUntilFail(ctx, opts, out)
+	}
+	return runGoTest(ctx, opts, out, rerunOpts{})
+}
+
+func validateOpts(opts *Options) error {
+	for _, err := range []error{
+		validateRerunOpts(opts),
+		validateRawCommandOpts(opts),
+		validateKeyValues("--env", opts.Env),
+		validateKeyValues("--junitfile-property", opts.JUnitProperties),
+		validateTrimPathPrefix(opts.TrimPathPrefix),
+		validateSummarySections(opts.Summary),
+		validateNoSummary(opts.NoSummary),
+		validateSummaryPosition(opts.SummaryPosition),
+		validateDryRun(opts),
+		validateUntilFail(opts),
+		validateLogFormat(opts.LogFormat),
+		validateKnownFlakyFile(opts),
+		validateFailOnOutput(opts),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	switch {
+	case opts.SlowPackageFail && opts.SlowPackageThreshold <= 0:
+		return errors.New("--slow-package-fail requires --slow-package-threshold")
+	case opts.QuietOnSuccess && opts.Watch:
+		return errors.New("--quiet-on-success can not be used with --watch")
+	case opts.JUnitFileOnStartupError && opts.JUnitFile == "":
+		return errors.New("--junitfile-on-startup-error requires --junitfile")
+	}
+	return validateGitHubActions(opts.GitHubActions)
+}
+
+// validateKeyValues returns an error if a value of the flag is not in the
+// form KEY=VALUE.
+func validateKeyValues(flag string, values []string) error {
+	for _, value := range values {
+		if strings.Index(value, "=") < 1 {
+			return errors.Errorf("invalid %s %q, must be KEY=VALUE", flag, value)
+		}
+	}
+	return nil
+}
+
+// runGoTest runs the go test command, or runs it for a single package when
+// target.pkg is set.
+func runGoTest(ctx context.Context, opts *Options, out io.Writer, target rerunOpts) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupt := handleInterrupts(cancel)
+	defer interrupt.stop()
+
+	streams, err := openStreamFiles(opts, false)
+	if err != nil {
+		return err
+	}
+	defer streams.close()
+	goTestProc, err := startGoTest(ctx, goTestCmdArgs(opts, target), opts.Env)
+	if err != nil {
+		return junitStartupError(opts, out, errors.Wrapf(err, "failed to run %s %s",
+			goTestProc.cmd.Path,
+			strings.Join(goTestProc.cmd.Args, " ")))
+	}
+	defer goTestProc.cancel()
+	streams.tee(&goTestProc)
+
+	rawOut := out
+	out = stdout(opts, out)
+	stderr := stderrOutput(opts)
+	handler, err := newEventHandler(opts, eventOutput(opts, out, stderr), stderr, rawOut)
+	if err != nil {
+		return err
+	}
+	defer handler.Close() // nolint: errcheck
+	limit := newFailureLimit(handler, maxFailures(opts), opts.PanicsFailFast, goTestProc.cancel)
+	goTestOut, goTestErr := goTestStreams(opts, goTestProc)
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:                goTestOut,
+		Stderr:                goTestErr,
+		Handler:               limit,
+		MaxTestOutputBytes:    opts.MaxTestOutputBytes,
+		IgnoreNonTestPackages: opts.IgnoreNonTestPackages,
+	})
+	if err != nil {
+		return err
+	}
+	exitErr := goTestProc.cmd.Wait()
+	switch stopErr := stoppedExitErr(opts, limit, interrupt); {
+	case stopErr != nil:
+		exitErr = stopErr
+	case exitErr != nil && opts.RerunFailsMaxAttempts > 0:
+		cfg := testjson.ScanConfig{Handler: handler, Execution: exec}
+		exitErr = rerunFailed(ctx, opts, cfg, exitErr, handler.reruns)
+	}
+	printStopReason(out, opts, limit, interrupt)
+	return finishRun(opts, out, handler, exec, exitErr)
+}
+
+// stoppedExitErr returns the error for a test run which was stopped before
+// all the tests were run, or nil if the test run was not stopped.
+func stoppedExitErr(opts *Options, limit *failureLimit, interrupt *interruptHandler) error {
+	switch sig := interrupt.interrupted(); {
+	case sig != nil:
+		return &exitError{
+			code:    signalExitCode(sig),
+			reason:  "interrupted",
+			stopped: true,
+			signal:  sig,
+		}
+	case limit.panicked != "":
+		return &exitError{code: 1, reason: "a test panicked with --panics-fail-fast", stopped: true}
+	case limit.reached() && opts.FailFast:
+		return &exitError{
+			code:    1,
+			reason:  "stopped at the first failure with --fail-fast",
+			stopped: true,
+		}
+	case limit.reached():
+		return &exitError{code: 1, reason: "reached --max-failures", stopped: true}
+	}
+	return nil
+}
+
+// goTestStreams returns the stream with the test2json events, and the stream
+// with the other output of the command. With --raw-command-json-stderr the
+// events are read from stderr, and stdout is handled as stderr.
+func goTestStreams(opts *Options, p proc) (io.Reader, io.Reader) {
+	events, other := p.stdout, p.stderr
+	if opts.RawCommandJSONStderr {
+		events, other = p.stderr, p.stdout
+	}
+	if isRawCommand(opts) {
+		events = newRawOutputChecker(events)
+	}
+	return events, other
+}
+
+// maxFailures returns the number of failed tests which stops the test run.
+// --fail-fast stops the run at the first failure, unless --max-failures is
+// also set.
+func maxFailures(opts *Options) int {
+	if opts.FailFast && opts.MaxFailures == 0 {
+		return 1
+	}
+	return opts.MaxFailures
+}
+
+// printStopReason prints the reason the test run was stopped before all the
+// tests were run.
+func printStopReason(
+	out io.Writer, opts *Options, limit *failureLimit, interrupt *interruptHandler,
+) {
+	switch sig := interrupt.interrupted(); {
+	case sig != nil:
+		fmt.Fprintln(out, color.RedString("\nTest run interrupted by %s", sig))
+	case limit.panicked != "":
+		fmt.Fprintln(out, color.RedString(
+			"\nTest run stopped early after a panic in %s (--panics-fail-fast)", limit.panicked))
+	case limit.reached() && opts.FailFast:
+		fmt.Fprintln(out, color.RedString(
+			"\nTest run stopped at the first failure (--fail-fast), "+
+				"the tests which did not run are not included in the counts"))
+	case limit.reached():
+		fmt.Fprintln(out, color.RedString(
+			"\nTest run stopped early after %d failures (--max-failures=%d)",
+			limit.count, limit.max))
+	}
+}
+
+// runFromFile reads the test2json output from a file instead of running go
+// test. The exit code is derived from the results of the Execution.
+func runFromFile(opts *Options, out io.Writer) error {
+	in, err := openInputFile(opts)
+	if err != nil {
+		return err
+	}
+	defer in.Close() // nolint: errcheck
+
+	rawOut := out
+	out = stdout(opts, out)
+	stderr := stderrOutput(opts)
+	handler, err := newEventHandler(opts, eventOutput(opts, out, stderr), stderr, rawOut)
+	if err != nil {
+		return err
+	}
+	defer handler.Close() // nolint: errcheck
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:                in,
+		Stderr:                strings.NewReader(""),
+		Handler:               handler,
+		MaxTestOutputBytes:    opts.MaxTestOutputBytes,
+		IgnoreNonTestPackages: opts.IgnoreNonTestPackages,
+	})
+	if err != nil {
+		return err
+	}
+	return finishRun(opts, out, handler, exec, executionExitErr(exec))
+}
+
+// openInputFile opens the file of test2json events from --raw-from-file,
+// converts the --junit-from-file into test2json events, or combines the events
+// of the files from --merge-json.
+func openInputFile(opts *Options) (io.ReadCloser, error) {
+	if opts.MergeJSON {
+		if opts.RawFromFile != "" || opts.JUnitFromFile != "" {
+			return nil, errors.New(
+				"--merge-json can not be used with --raw-from-file or --junit-from-file")
+		}
+		return openMergedFiles(opts.Args)
+	}
+	if opts.JUnitFromFile != "" {
+		switch {
+		case len(opts.Args) > 0:
+			return nil, errors.New("go test args can not be used with --junit-from-file")
+		case opts.RawFromFile != "" || isRawCommand(opts):
+			return nil, errors.New(
+				"--junit-from-file can not be used with --raw-from-file or --raw-command")
+		}
+		return openJUnitFile(opts.JUnitFromFile)
+	}
+	if len(opts.Args) > 0 {
+		return nil, errors.New("go test args can not be used with --raw-from-file")
+	}
+	return openRawFile(opts.RawFromFile)
+}
+
+func openRawFile(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	in, err := os.Open(filename)
+	return in, errors.Wrap(err, "failed to open raw input file")
+}
+
+// executionExitErr returns an error with a non-zero exit code if any test or
+// package failed.
+func executionExitErr(exec *testjson.Execution) error {
+	if len(exec.Failed()) > 0 || len(exec.Errors()) > 0 {
+		return &exitError{code: 1, reason: "tests failed"}
+	}
+	return nil
+}
+
+// noTestsExitErr returns an error with a non-zero exit code when
+// --no-tests-fail is set and no tests were run. Otherwise exitErr is returned.
+func noTestsExitErr(opts *Options, out io.Writer, exec *testjson.Execution, exitErr error) error {
+	if !opts.NoTestsFail || exec.Total() > 0 {
+		return exitErr
+	}
+	fmt.Fprintln(out, color.RedString("No tests were run (--no-tests-fail)"))
+	if exitErr != nil {
+		return exitErr
+	}
+	return &exitError{code: 1, reason: "no tests were run"}
+}
+
+// skippedExitErr returns an error with a non-zero exit code when
+// --skipped-fail is set and any tests were skipped. The skipped tests are
+// listed even when the skipped section of the summary is hidden. Otherwise
+// exitErr is returned.
+func skippedExitErr(opts *Options, out io.Writer, exec *testjson.Execution, exitErr error) error {
+	skipped := exec.Skipped()
+	if !opts.SkippedFail || len(skipped) == 0 {
+		return exitErr
+	}
+	fmt.Fprintln(out, color.RedString("\nSkipped tests are not allowed (--skipped-fail):"))
+	for _, tc := range skipped {
+		fmt.Fprintf(out, "%s %s\n", testjson.RelativePackagePath(tc.Package), tc.Test)
+	}
+	if exitErr != nil {
+		return exitErr
+	}
+	return &exitError{code: 1, reason: "tests were skipped"}
+}
+
+// printReports prints the optional reports which follow the summary.
+func printReports(opts *Options, out io.Writer, exec *testjson.Execution) error {
+	reports := []struct {
+		enabled bool
+		print   func() error
+	}{
+		{enabled: opts.Slowest > 0, print: func() error {
+			return testjson.PrintSlowest(out, exec, opts.Slowest)
+		}},
+		{enabled: opts.SlowPackageThreshold > 0, print: func() error {
+			return testjson.PrintSlowPackages(out, exec, opts.SlowPackageThreshold)
+		}},
+		{enabled: opts.TimingHistogram, print: func() error {
+			return testjson.PrintTimingHistogram(out, exec, opts.TimingHistogramBounds)
+		}},
+		{enabled: opts.ShowFailuresLast, print: func() error {
+			return testjson.PrintFailureOutput(out, exec, failureOutputLimit(opts))
+		}},
+		{enabled: opts.HighlightFirstFailure, print: func() error {
+			return testjson.PrintFirstFailure(out, exec)
+		}},
+		{enabled: parallelismEnabled(opts), print: func() error {
+			printParallelism(out, opts, exec)
+			return nil
+		}},
+		{enabled: githubActionsEnabled(opts), print: func() error {
+			printGitHubAnnotations(opts, out, exec)
+			return nil
+		}},
+	}
+	for _, report := range reports {
+		if !report.enabled {
+			continue
+		}
+		if err := report.print(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slowPackagesExitErr returns an error with a non-zero exit code when
+// --slow-package-fail is set and a package exceeded --slow-package-threshold.
+// Otherwise exitErr is returned.
+func slowPackagesExitErr(opts *Options, exec *testjson.Execution, exitErr error) error {
+	if !opts.SlowPackageFail || exitErr != nil {
+		return exitErr
+	}
+	if len(testjson.SlowPackages(exec, opts.SlowPackageThreshold)) == 0 {
+		return nil
+	}
+	return &exitError{code: 1, reason: "packages exceeded --slow-package-threshold"}
+}
+
+// stdout returns the writer used for the formatted output and the summary.
+func stdout(opts *Options, out io.Writer) io.Writer {
+	progress := progressEnabled(opts, out)
+	if len(opts.TrimPathPrefix) > 0 {
+		out = newTrimPathWriter(out, opts.TrimPathPrefix)
+	}
+	if opts.HighlightDiffs {
+		out = newHighlightWriter(out)
+	}
+	if progress {
+		out = newProgressWriter(out, terminalWidth(os.Stdout))
+	}
+	return out
+}
+
+// eventOutput returns the writer used for the formatted output of the test
+// events. With --output-to-stderr the events are written to stderr, and out is
+// only used for the summary and the reports which follow it.
+func eventOutput(opts *Options, out io.Writer, stderr io.Writer) io.Writer {
+	if opts.OutputToStderr {
+		return stdout(opts, stderr)
+	}
+	return out
+}
+
+// failureOutputLimit returns the limits of the output printed by
+// --show-failures-last. The output of each test is limited to a tenth of the
+// total, so that a single test with a lot of output does not hide the others.
+func failureOutputLimit(opts *Options) testjson.FailureOutputLimit {
+	total := int(opts.ShowFailuresLastLimit)
+	if total == 0 {
+		total = 1 << 20
+	}
+	return testjson.FailureOutputLimit{Total: total, PerTest: total / 10}
+}
+
+func finishRun(
+	opts *Options,
+	out io.Writer,
+	handler *eventHandler,
+	exec *testjson.Execution,
+	exitErr error,
+) error {
+	if opts.Format == "tap" {
+		out = printTAPPlan(opts, out, handler, exec)
+	}
+	printPassedPackages(opts, out, exec)
+	if err := summarizer(opts)(out, exec); err != nil {
+		return err
+	}
+	exitErr = knownFlakyExitErr(opts, out, exec, exitErr)
+	exitErr = noTestsExitErr(opts, out, exec, exitErr)
+	exitErr = skippedExitErr(opts, out, exec, exitErr)
+	exitErr = failOnOutputExitErr(out, handler.failOnOutput, exitErr)
+	if err := printReports(opts, out, exec); err != nil {
+		return err
+	}
+	exitErr = slowPackagesExitErr(opts, exec, exitErr)
+	reportErr := notifyObservers(opts, out, exec, handler.reruns)
+	if err := postRunHook(opts, exec, exitErr); err != nil {
+		log.WithError(err).Warn("post run command failed")
+	}
+	switch {
+	case reportErr == nil:
+		return exitErr
+	case exitErr != nil:
+		// the exit code of the test run is more important than the report
+		log.Error(reportErr.Error())
+		return exitErr
+	}
+	return reportErr
+}
+
+// printTAPPlan prints the plan at the end of the TAP stream, and returns the
+// writer for the summary. The summary is not TAP, so it is printed to stderr
+// when the TAP stream is written to out, and the plan is the last line of the
+// stream.
+func printTAPPlan(
+	opts *Options,
+	out io.Writer,
+	handler *eventHandler,
+	exec *testjson.Execution,
+) io.Writer {
+	fmt.Fprint(handler.out, testjson.TAPPlan(exec))
+	if opts.OutputToStderr {
+		return out
+	}
+	return stderrOutput(opts)
+}
+
+func goTestCmdArgs(opts *Options, rerun rerunOpts) []string {
+	args := opts.Args
+	defaultArgs := append([]string{goBinary(opts)}, goTestSubcommand(opts)...)
+	if opts.FailFast {
+		defaultArgs = append(defaultArgs, "-failfast")
+	}
+	switch {
+	case opts.RawCommandShell:
+		return append([]string{"sh", "-c"}, args...)
+	case opts.RawCommand:
+		return args
+	case rerun.pkg != "":
+		cmd := append(defaultArgs, "-json")
+		cmd = append(append(cmd, opts.DefaultArgs...), args...)
+		return append(cmd, rerun.Args()...)
+	case len(args) == 0:
+		cmd := append(append(defaultArgs, "-json"), opts.DefaultArgs...)
+		return append(cmd, testPackages(opts, "./...")...)
+	case !hasJSONArg(opts, args):
+		defaultArgs = append(defaultArgs, "-json")
+	}
+	cmd := append(append(defaultArgs, opts.DefaultArgs...), args...)
+	return append(cmd, testPackages(opts, "")...)
+}
+
+// isRawCommand returns true if the command is run as is, without adding the
+// go test -json arguments.
+func isRawCommand(opts *Options) bool {
+	return opts.RawCommand || opts.RawCommandShell
+}
+
+// goBinary returns the go executable used to run go test.
+func goBinary(opts *Options) string {
+	if opts.GoBinary == "" {
+		return "go"
+	}
+	return opts.GoBinary
+}
+
+// goTestSubcommand returns the subcommand, and any arguments which are part
+// of the subcommand, used in place of test.
+func goTestSubcommand(opts *Options) []string {
+	if opts.GoTestSubcommand == "" {
+		return []string{"test"}
+	}
+	return strings.Fields(opts.GoTestSubcommand)
+}
+
+// testPackages returns the list of packages to test. The packages set with
+// --packages take precedence over TEST_DIRECTORY, which takes precedence over
+// defaultPath.
+func testPackages(opts *Options, defaultPath string) []string {
+	if len(opts.Packages) > 0 {
+		return opts.Packages
+	}
+	if path := pathFromEnv(defaultPath); path != "" {
+		return []string{path}
+	}
+	return nil
+}
+
+func pathFromEnv(defaultPath string) string {
+	return lookEnvWithDefault("TEST_DIRECTORY", defaultPath)
+}
+
+// hasJSONArg returns true if args already enable JSON output, so that -json is
+// not added to the go test command a second time. The -json flag is always
+// recognized, other flags are recognized by opts.HasJSONArg.
+func hasJSONArg(opts *Options, args []string) bool {
+	if opts.HasJSONArg != nil && opts.HasJSONArg(args) {
+		return true
+	}
+	for _, arg := range args {
+		if isJSONFlag(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSONFlag returns true for the -json flag of go test, with one or two
+// dashes and an optional boolean value.
+func isJSONFlag(arg string) bool {
+	switch trimFlagDashes(arg) {
+	case "json", "json=true", "json=1":
+		return true
+	}
+	return false
+}
+
+// trimFlagDashes removes the one or two dashes from the start of a flag. An
+// argument which is not a flag is returned as an empty string.
+func trimFlagDashes(arg string) string {
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		return arg[2:]
+	case strings.HasPrefix(arg, "-"):
+		return arg[1:]
+	}
+	return ""
+}
+
+type proc struct {
+	cmd    *exec.Cmd
+	stdout io.Reader
+	stderr io.Reader
+	cancel func()
+}
+
+// startGoTest starts the go test command. The variables in env, in the form
+// KEY=VALUE, are set in the environment of the command, in addition to the
+// environment of gotestsum.
+func startGoTest(ctx context.Context, args []string, env []string) (proc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	p := proc{
+		cmd:    exec.CommandContext(ctx, args[0], args[1:]...),
+		cancel: cancel,
+	}
+	if len(env) > 0 {
+		p.cmd.Env = append(os.Environ(), env...)
+	}
+	log.Debugf("exec: %s", p.cmd.Args)
+	var err error
+	p.stdout, err = p.cmd.StdoutPipe()
+	if err != nil {
+		return p, err
+	}
+	p.stderr, err = p.cmd.StderrPipe()
+	if err != nil {
+		return p, err
+	}
+	err = p.cmd.Start()
+	if err == nil {
+		log.Debugf("go test pid: %d", p.cmd.Process.Pid)
+	}
+	return p, err
+}
+
+// summarySectionNames maps the values of --summary and --no-summary to the
+// sections of the summary.
+var summarySectionNames = map[string]testjson.Summary{
+	"failed":  testjson.SummarizeFailed,
+	"skipped": testjson.SummarizeSkipped,
+	"errors":  testjson.SummarizeErrors,
+	"race":    testjson.SummarizeRaces,
+	"panic":   testjson.SummarizePanics,
+	"timeout": testjson.SummarizeTimeouts,
+}
+
+// summarySections returns the sections of the summary to print. When
+// --summary is set only those sections are printed, otherwise all sections are
+// printed. The sections from --no-summary are removed from either.
+func summarySections(opts *Options) testjson.Summary {
+	summary := testjson.SummarizeAll
+	if len(opts.Summary) > 0 {
+		summary = 0
+		for _, item := range opts.Summary {
+			summary |= summarySectionNames[item]
+		}
+	}
+	for _, item := range opts.NoSummary {
+		switch item {
+		case "all":
+			summary &^= testjson.SummarizeAll
+		case "output":
+			summary |= testjson.SummarizeHideOutput
+		default:
+			summary &^= summarySectionNames[item]
+		}
+	}
+	return summary
+}
+
+// validateSummarySections returns an error if a value of --summary is not the
+// name of a section.
+func validateSummarySections(sections []string) error {
+	for _, item := range sections {
+		if _, ok := summarySectionNames[item]; !ok {
+			return errors.Errorf(
+				"invalid --summary %q, must be one of: "+
+					"failed, skipped, errors, race, panic, timeout", item)
+		}
+	}
+	return nil
+}
+
+// validateNoSummary returns an error if a value of --no-summary is not the
+// name of a section, all, or output.
+func validateNoSummary(values []string) error {
+	for _, item := range values {
+		if _, ok := summarySectionNames[item]; ok || item == "all" || item == "output" {
+			continue
+		}
+		return errors.Errorf(
+			"invalid --no-summary %q, must be one of: "+
+				"failed, skipped, errors, race, panic, timeout, all, output", item)
+	}
+	return nil
+}
+
+// validateSummaryPosition returns an error if the value of --summary-position
+// is not top or bottom.
+func validateSummaryPosition(value string) error {
+	switch value {
+	case "", "top", "bottom":
+		return nil
+	}
+	return errors.Errorf("invalid --summary-position value %s, must be one of: top, bottom", value)
+}
+
+func summarizer(opts *Options) func(io.Writer, *testjson.Execution) error {
+	summary := summarySections(opts)
+	if opts.DedupeFailures {
+		summary |= testjson.SummarizeDedupeFailures
+	}
+	if opts.ShowSkipReasons {
+		summary |= testjson.SummarizeSkipReasons
+	}
+	if opts.Coverage {
+		summary |= testjson.SummarizeCoverage
+	}
+	if opts.SortFailures {
+		summary |= testjson.SummarizeSortFailures
+	}
+	if opts.GroupSubtests {
+		summary |= testjson.SummarizeGroupSubtests
+	}
+	if opts.SummaryPosition == "top" {
+		summary |= testjson.SummarizeDoneFirst
+	}
+	printSummary := testjson.PrintSummary
+	if opts.CompactSummary {
+		printSummary = testjson.PrintCompactSummary
+	}
+	return func(out io.Writer, exec *testjson.Execution) error {
+		if err := printSummary(out, exec, summary); err != nil {
+			return err
+		}
+		// the note is part of the summary, and is hidden with the sections
+		if summary&testjson.SummarizeAll != 0 {
+			printTestFilters(out, opts)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,851 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestRun_RawFromFile(t *testing.T) {
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "../testjson/testdata/go-test-json.out",
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Assert(t, IsExitError(err))
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), "=== Failed"))
+}
+
+func TestRun_TAPPlanIsLastLine(t *testing.T) {
+	defer patchNoColor(true)()
+	stderr := new(bytes.Buffer)
+	opts := Options{
+		Format:      "tap",
+		RawFromFile: "../testjson/testdata/go-test-json.out",
+		stderr:      stderr,
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Assert(t, strings.HasPrefix(lines[len(lines)-1], "1.."), out.String())
+	assert.Assert(t, !strings.Contains(out.String(), "DONE"), out.String())
+	assert.Assert(t, cmp.Contains(stderr.String(), "=== Failed"))
+	assert.Assert(t, cmp.Contains(stderr.String(), "DONE"))
+}
+
+func TestGoTestCmdArgs_GoBinary(t *testing.T) {
+	opts := &Options{GoBinary: "/opt/go1.21/bin/go", Args: []string{"./pkg"}}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"/opt/go1.21/bin/go", "test", "-json", "./pkg"})
+
+	opts = &Options{}
+	args = goTestCmdArgs(opts, rerunOpts{pkg: "./pkg"})
+	assert.DeepEqual(t, args, []string{"go", "test", "-json", "./pkg"})
+}
+
+func TestGoTestCmdArgs_GoTestSubcommand(t *testing.T) {
+	opts := &Options{GoTestSubcommand: "tool-test", Args: []string{"./pkg"}}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "tool-test", "-json", "./pkg"})
+
+	opts = &Options{GoTestSubcommand: "tool mytest"}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "tool", "mytest", "-json", "./..."})
+}
+
+func TestGoTestCmdArgs_RawCommandShell(t *testing.T) {
+	opts := &Options{
+		RawCommandShell: true,
+		Args:            []string{"go test -json ./... | tee $OUT"},
+	}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"sh", "-c", "go test -json ./... | tee $OUT"})
+}
+
+func TestGoTestCmdArgs_Packages(t *testing.T) {
+	opts := &Options{Packages: []string{"./foo/...", "./bar"}}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "test", "-json", "./foo/...", "./bar"})
+
+	opts.Args = []string{"-tags=integration"}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	expected := []string{"go", "test", "-json", "-tags=integration", "./foo/...", "./bar"}
+	assert.DeepEqual(t, args, expected)
+}
+
+func TestHasJSONArg(t *testing.T) {
+	for _, arg := range []string{"-json", "--json", "-json=true", "-json=1"} {
+		assert.Assert(t, hasJSONArg(&Options{}, []string{"-count=1", arg}), arg)
+	}
+	for _, arg := range []string{"-json=false", "-test.v=test2json", "json"} {
+		assert.Assert(t, !hasJSONArg(&Options{}, []string{"-count=1", arg}), arg)
+	}
+}
+
+func TestHasJSONArg_CustomCheck(t *testing.T) {
+	opts := &Options{
+		Args: []string{"-test.json", "./pkg"},
+		HasJSONArg: func(args []string) bool {
+			for _, arg := range args {
+				if arg == "-test.json" {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "test", "-test.json", "./pkg"})
+
+	opts.Args = []string{"-count=1", "./pkg"}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "test", "-json", "-count=1", "./pkg"})
+}
+
+func TestRun_RawCommandJSONStderr(t *testing.T) {
+	defer patchNoColor(true)()
+	script := `echo "other data"
+cat testdata/skipped.json >&2`
+	opts := Options{
+		Format:               "short",
+		RawCommand:           true,
+		RawCommandJSONStderr: true,
+		Args:                 []string{"sh", "-c", script},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped, 1 error"))
+
+	opts = Options{RawCommandJSONStderr: true, Args: []string{"./..."}}
+	err := Run(context.Background(), opts, out)
+	assert.Error(t, err, "--raw-command-json-stderr requires --raw-command")
+}
+
+func TestGoTestCmdArgs_FailFast(t *testing.T) {
+	opts := &Options{FailFast: true, Args: []string{"./pkg"}}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "test", "-failfast", "-json", "./pkg"})
+
+	opts = &Options{FailFast: true, RawCommand: true, Args: []string{"./script"}}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"./script"})
+}
+
+func TestGoTestCmdArgs_DefaultArgs(t *testing.T) {
+	defaults := []string{"-count=1", "-race"}
+	opts := &Options{DefaultArgs: defaults, Args: []string{"-count=2", "./pkg"}}
+	args := goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args,
+		[]string{"go", "test", "-json", "-count=1", "-race", "-count=2", "./pkg"})
+
+	opts = &Options{DefaultArgs: defaults}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"go", "test", "-json", "-count=1", "-race", "./..."})
+
+	args = goTestCmdArgs(opts, rerunOpts{pkg: "./pkg"})
+	assert.DeepEqual(t, args, []string{"go", "test", "-json", "-count=1", "-race", "./pkg"})
+
+	opts = &Options{DefaultArgs: defaults, RawCommand: true, Args: []string{"./script"}}
+	args = goTestCmdArgs(opts, rerunOpts{})
+	assert.DeepEqual(t, args, []string{"./script"})
+}
+
+func TestMain_InvalidDefaultArgs(t *testing.T) {
+	os.Setenv("GOTESTSUM_ARGS", `-run "TestA`) // nolint: errcheck
+	defer os.Unsetenv("GOTESTSUM_ARGS")        // nolint: errcheck
+	err := Main("gotestsum", nil)
+	assert.ErrorContains(t, err, "invalid GOTESTSUM_ARGS")
+}
+
+func TestRun_FailFast(t *testing.T) {
+	defer patchNoColor(true)()
+	script := `echo '{"Action":"run","Package":"pkg","Test":"TestOne"}'
+echo '{"Action":"fail","Package":"pkg","Test":"TestOne"}'
+exec sleep 5`
+	opts := Options{
+		Format:     "short",
+		FailFast:   true,
+		RawCommand: true,
+		Args:       []string{"sh", "-c", script},
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), "stopped at the first failure (--fail-fast)"))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 1 tests, 1 failure"))
+}
+
+func TestRun_MaxFailures(t *testing.T) {
+	defer patchNoColor(true)()
+	script := `echo '{"Action":"run","Package":"pkg","Test":"TestOne"}'
+echo '{"Action":"fail","Package":"pkg","Test":"TestOne"}'
+echo '{"Action":"run","Package":"pkg","Test":"TestTwo"}'
+echo '{"Action":"fail","Package":"pkg","Test":"TestTwo"}'
+exec sleep 5`
+	opts := Options{
+		Format:      "short",
+		MaxFailures: 2,
+		RawCommand:  true,
+		Args:        []string{"sh", "-c", script},
+	}
+	out := new(bytes.Buffer)
+	start := time.Now()
+	err := Run(context.Background(), opts, out)
+	assert.Assert(t, time.Since(start) < 4*time.Second, "go test was not cancelled")
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(),
+		"Test run stopped early after 2 failures (--max-failures=2)"))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 2 failures"))
+}
+
+func TestNoColorFromEnv(t *testing.T) {
+	var testcases = []struct {
+		env      map[string]string
+		expected bool
+	}{
+		{env: map[string]string{}, expected: false},
+		{env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{env: map[string]string{"NO_COLOR": ""}, expected: false},
+		{env: map[string]string{"GOTESTSUM_NO_COLOR": "true"}, expected: true},
+		{env: map[string]string{"GOTESTSUM_NO_COLOR": "false", "NO_COLOR": "1"}, expected: false},
+	}
+	for _, tc := range testcases {
+		reset := patchEnv(tc.env)
+		assert.Equal(t, noColorFromEnv(), tc.expected, "env: %v", tc.env)
+		reset()
+	}
+}
+
+func TestForceColorFromEnv(t *testing.T) {
+	var testcases = []struct {
+		env      map[string]string
+		expected bool
+	}{
+		{env: map[string]string{}, expected: false},
+		{env: map[string]string{"FORCE_COLOR": "1"}, expected: true},
+		{env: map[string]string{"FORCE_COLOR": ""}, expected: true},
+		{env: map[string]string{"FORCE_COLOR": "0"}, expected: false},
+		{env: map[string]string{"FORCE_COLOR": "false"}, expected: false},
+	}
+	for _, tc := range testcases {
+		reset := patchEnv(tc.env)
+		assert.Equal(t, forceColorFromEnv(), tc.expected, "env: %v", tc.env)
+		reset()
+	}
+}
+
+func TestSetupLogging_ForceColor(t *testing.T) {
+	defer patchNoColor(true)()
+
+	setupLogging(&Options{ForceColor: true})
+	assert.Assert(t, !color.NoColor)
+
+	setupLogging(&Options{ForceColor: true, NoColor: true})
+	assert.Assert(t, color.NoColor)
+}
+
+// patchEnv unsets the color environment variables, and then sets env. The
+// returned function restores the original values.
+func patchEnv(env map[string]string) func() {
+	orig := map[string]string{}
+	for _, key := range []string{"NO_COLOR", "GOTESTSUM_NO_COLOR", "FORCE_COLOR", "TERM"} {
+		if value, ok := os.LookupEnv(key); ok {
+			orig[key] = value
+		}
+		os.Unsetenv(key) // nolint: errcheck
+	}
+	for key, value := range env {
+		os.Setenv(key, value) // nolint: errcheck
+	}
+	return func() {
+		for key := range env {
+			os.Unsetenv(key) // nolint: errcheck
+		}
+		for key, value := range orig {
+			os.Setenv(key, value) // nolint: errcheck
+		}
+	}
+}
+
+func TestSetupLogging_DumbTerminal(t *testing.T) {
+	defer patchNoColor(false)()
+	defer patchEnv(map[string]string{"TERM": "dumb"})()
+
+	setupLogging(&Options{})
+	assert.Assert(t, color.NoColor)
+
+	setupLogging(&Options{ForceColor: true})
+	assert.Assert(t, !color.NoColor)
+
+	defer patchEnv(map[string]string{"TERM": "xterm-256color"})()
+	setupLogging(&Options{})
+	assert.Assert(t, !color.NoColor)
+}
+
+func TestRun_RestoresGlobals(t *testing.T) {
+	defer patchNoColor(false)()
+	level, formatter := log.GetLevel(), log.StandardLogger().Formatter
+	prefix := testjson.PackagePathPrefix()
+
+	opts := Options{
+		Format:            "short",
+		RawFromFile:       "../testjson/testdata/go-test-json.out",
+		NoColor:           true,
+		Debug:             true,
+		LogFormat:         "json",
+		FormatPackagePath: "full",
+	}
+	err := Run(context.Background(), opts, new(bytes.Buffer))
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+
+	assert.Assert(t, !color.NoColor)
+	assert.Equal(t, log.GetLevel(), level)
+	assert.Equal(t, log.StandardLogger().Formatter, formatter)
+	assert.Equal(t, testjson.PackagePathPrefix(), prefix)
+}
+
+func TestRun_SkippedFail(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		NoSummary:   []string{"skipped"},
+		SkippedFail: true,
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(),
+		"Skipped tests are not allowed (--skipped-fail):\nexample.com/pkg TestSkipped\n"))
+}
+
+func TestRun_QuietOnSuccess(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:         "short",
+		RawFromFile:    "testdata/skipped.json",
+		QuietOnSuccess: true,
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Equal(t, out.String(), "")
+
+	opts.SkippedFail = true
+	err := Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped"))
+}
+
+func TestRunQuietOnSuccess_Stderr(t *testing.T) {
+	defer patchNoColor(true)()
+	stderr := new(bytes.Buffer)
+	opts := &Options{
+		Format:          "short",
+		RawCommandShell: true,
+		Args:            []string{"cat testdata/skipped.json; echo warning from go >&2"},
+		OutputToStderr:  true,
+		stderr:          stderr,
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, runQuietOnSuccess(context.Background(), opts, out))
+	assert.Equal(t, stderr.String(), "")
+	assert.Equal(t, out.String(), "")
+
+	opts.SkippedFail = true
+	err := runQuietOnSuccess(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(stderr.String(), "warning from go"))
+	assert.Assert(t, cmp.Contains(stderr.String(), "example.com/pkg"))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped"))
+}
+
+func TestRun_Observers(t *testing.T) {
+	var observed *testjson.Execution
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		Observers: []ExecutionObserver{
+			ExecutionObserverFunc(func(execution *testjson.Execution) error {
+				observed = execution
+				return nil
+			}),
+		},
+	}
+	assert.NilError(t, Run(context.Background(), opts, ioutil.Discard))
+	assert.Assert(t, observed != nil)
+	assert.Equal(t, observed.Total(), 2)
+
+	opts.Observers = []ExecutionObserver{
+		ExecutionObserverFunc(func(*testjson.Execution) error {
+			return errors.New("upload failed")
+		}),
+	}
+	err := Run(context.Background(), opts, ioutil.Discard)
+	assert.Error(t, err, "upload failed")
+}
+
+func TestRun_JUnitFromFile(t *testing.T) {
+	defer patchNoColor(true)()
+
+	opts := Options{Format: "short", JUnitFromFile: "testdata/junit.xml"}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+
+	expected := `✖  example.com/pkg (120ms)
+∅  example.com/empty
+
+=== Skipped
+=== SKIP: example.com/pkg TestSkipped (0.00s)
+pkg_test.go:20: requires docker
+
+
+=== Failed
+=== FAIL: example.com/pkg TestFailed (0.10s)
+pkg_test.go:12: expected 1, got 2
+
+
+DONE 3 tests, 1 skipped, 1 failure in `
+	assert.Assert(t, strings.HasPrefix(out.String(), expected), out.String())
+	assert.Assert(t, cmp.Contains(out.String(), "0.110s sum of test time"))
+}
+
+func TestRun_JUnitFromFileInvalid(t *testing.T) {
+	opts := Options{Format: "short", JUnitFromFile: "testdata/skipped.json"}
+	err := Run(context.Background(), opts, ioutil.Discard)
+	assert.ErrorContains(t, err, "failed to read testdata/skipped.json: failed to parse JUnit XML")
+}
+
+func TestRun_OutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-output")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		OutputFile:  filepath.Join(dir, "output.txt"),
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+
+	raw, err := ioutil.ReadFile(opts.OutputFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), out.String())
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped"))
+}
+
+func TestRun_MarkdownSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-markdown")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:              "short",
+		RawFromFile:         "testdata/skipped.json",
+		MarkdownSummaryFile: filepath.Join(dir, "summary.md"),
+	}
+	assert.NilError(t, Run(context.Background(), opts, new(bytes.Buffer)))
+
+	raw, err := ioutil.ReadFile(opts.MarkdownSummaryFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), "**2 tests**, 1 passed, 0 failed, 1 skipped"))
+	assert.Assert(t, cmp.Contains(string(raw), "| ✅ pass | 1 | 0 | 1 |"))
+}
+
+func TestRun_TraceFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-trace")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		TraceFile:   filepath.Join(dir, "trace.json"),
+	}
+	assert.NilError(t, Run(context.Background(), opts, new(bytes.Buffer)))
+
+	raw, err := ioutil.ReadFile(opts.TraceFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), `"traceEvents"`))
+}
+
+func TestRun_JUnitFileNotWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-junit")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// a file can not be created under a path which is a file
+	notDir := filepath.Join(dir, "file")
+	assert.NilError(t, ioutil.WriteFile(notDir, nil, 0644))
+	opts := Options{
+		Format:          "short",
+		RawFromFile:     "testdata/skipped.json",
+		JUnitFile:       filepath.Join(notDir, "junit.xml"),
+		JSONSummaryFile: filepath.Join(dir, "summary.json"),
+	}
+	out := new(bytes.Buffer)
+	err = Run(context.Background(), opts, out)
+	assert.ErrorContains(t, err, "failed to open JUnit file")
+	assert.Assert(t, !IsExitError(err))
+	assert.Equal(t, InternalErrorExitCode(err), 4)
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped"))
+
+	// the other reports are still written
+	_, err = os.Stat(opts.JSONSummaryFile)
+	assert.NilError(t, err)
+}
+
+func TestRun_JUnitFileNotWritableWithFailedTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-junit")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	notDir := filepath.Join(dir, "file")
+	assert.NilError(t, ioutil.WriteFile(notDir, nil, 0644))
+	opts := Options{
+		Format:          "short",
+		RawCommandShell: true,
+		Args: []string{`echo '{"Action":"fail","Package":"example.com/pkg","Test":"TestA"}'
+exit 1`},
+		JUnitFile: filepath.Join(notDir, "junit.xml"),
+	}
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.Assert(t, IsExitError(err), err)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+}
+
+func TestReportError_Cause(t *testing.T) {
+	first := errors.New("failed to open JUnit file")
+	err := &reportError{errs: []error{first, errors.New("failed to write trace file")}}
+	assert.Error(t, err, "failed to open JUnit file; failed to write trace file")
+	assert.Equal(t, errors.Cause(err), first)
+	assert.Equal(t, len(err.Errors()), 2)
+}
+
+func TestRun_JUnitFileOnStartupError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-startup")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:                  "short",
+		GoBinary:                filepath.Join(dir, "missing-go"),
+		JUnitFile:               filepath.Join(dir, "junit.xml"),
+		JUnitFileOnStartupError: true,
+	}
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "failed to run")
+
+	raw, err := ioutil.ReadFile(opts.JUnitFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), `errors="1"`))
+	assert.Assert(t, cmp.Contains(string(raw), "missing-go"))
+}
+
+func TestRun_JUnitFileOnStartupErrorAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-startup")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:                  "short",
+		GoBinary:                filepath.Join(dir, "missing-go"),
+		JUnitFile:               filepath.Join(dir, "junit.xml"),
+		JUnitFileOnStartupError: true,
+		Append:                  true,
+	}
+	previous := `<testsuites><testsuite name="previous"></testsuite></testsuites>`
+	assert.NilError(t, ioutil.WriteFile(opts.JUnitFile, []byte(previous), 0644))
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "failed to run")
+
+	raw, err := ioutil.ReadFile(opts.JUnitFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(raw), `name="previous"`))
+	assert.Assert(t, cmp.Contains(string(raw), `errors="1"`))
+}
+
+func TestRun_JUnitFileOnStartupErrorStdout(t *testing.T) {
+	opts := Options{
+		Format:                  "short",
+		GoBinary:                "/missing/go",
+		JUnitFile:               "-",
+		JUnitFileOnStartupError: true,
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.ErrorContains(t, err, "failed to run")
+	assert.Assert(t, cmp.Contains(out.String(), "StartupError"))
+}
+
+func TestRun_Append(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-append")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		JSONFile:    filepath.Join(dir, "events.json"),
+		JUnitFile:   filepath.Join(dir, "junit.xml"),
+		Append:      true,
+	}
+	for i := 0; i < 2; i++ {
+		assert.NilError(t, Run(context.Background(), opts, ioutil.Discard))
+	}
+
+	events, err := ioutil.ReadFile(opts.JSONFile)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(events), "\n"), 10)
+
+	junit, err := ioutil.ReadFile(opts.JUnitFile)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(junit), "<testsuite "), 2)
+}
+
+func TestRun_JUnitFileStdout(t *testing.T) {
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		JUnitFile:   "-",
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Assert(t, cmp.Contains(out.String(), "<testsuites"))
+}
+
+func TestRun_AppendInvalidJUnitFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-append")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		JUnitFile:   filepath.Join(dir, "junit.xml"),
+		Append:      true,
+	}
+	invalid := "<testsuites><testsuite name=\"previous\">"
+	assert.NilError(t, ioutil.WriteFile(opts.JUnitFile, []byte(invalid), 0644))
+	err = Run(context.Background(), opts, ioutil.Discard)
+	assert.ErrorContains(t, err, "failed to read existing JUnit file")
+
+	junit, err := ioutil.ReadFile(opts.JUnitFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(junit), invalid)
+}
+
+func TestRun_AppendSingleTestSuiteJUnitFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-append")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	opts := Options{
+		Format:      "short",
+		RawFromFile: "testdata/skipped.json",
+		JUnitFile:   filepath.Join(dir, "junit.xml"),
+		Append:      true,
+	}
+	previous := `<testsuite name="previous" tests="1"></testsuite>`
+	assert.NilError(t, ioutil.WriteFile(opts.JUnitFile, []byte(previous), 0644))
+	assert.NilError(t, Run(context.Background(), opts, ioutil.Discard))
+
+	junit, err := ioutil.ReadFile(opts.JUnitFile)
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(junit), `name="previous"`))
+	assert.Equal(t, strings.Count(string(junit), "<testsuite "), 2)
+}
+
+func TestValidateKeyValues(t *testing.T) {
+	assert.NilError(t, validateKeyValues("--env", []string{"CGO_ENABLED=0", "EMPTY="}))
+	assert.Error(t, validateKeyValues("--env", []string{"CGO_ENABLED"}),
+		`invalid --env "CGO_ENABLED", must be KEY=VALUE`)
+	assert.Error(t, validateKeyValues("--junitfile-property", []string{"=value"}),
+		`invalid --junitfile-property "=value", must be KEY=VALUE`)
+}
+
+func TestStartGoTest_Env(t *testing.T) {
+	args := []string{"sh", "-c", "printf %s \"$GOTESTSUM_TEST_ENV\""}
+	proc, err := startGoTest(context.Background(), args, []string{"GOTESTSUM_TEST_ENV=value"})
+	assert.NilError(t, err)
+	defer proc.cancel()
+
+	out, err := ioutil.ReadAll(proc.stdout)
+	assert.NilError(t, err)
+	assert.NilError(t, proc.cmd.Wait())
+	assert.Equal(t, string(out), "value")
+}
+
+func TestMaxLineWidth(t *testing.T) {
+	_, opts := setupFlags("gotestsum")
+	assert.Equal(t, opts.MaxLineWidth, 0)
+	assert.Equal(t, maxLineWidth(opts), defaultLineWidth())
+
+	assert.Equal(t, maxLineWidth(&Options{MaxLineWidth: 80}), 80)
+	assert.Equal(t, maxLineWidth(&Options{MaxLineWidth: -1}), 0)
+}
+
+func TestPrintVersion(t *testing.T) {
+	out := new(bytes.Buffer)
+	printVersion(out, "gotestsum")
+	assert.Equal(t, out.String(), "gotestsum version dev (commit unknown, built unknown)\n")
+}
+
+func TestEventOutput(t *testing.T) {
+	out, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+	assert.Equal(t, eventOutput(&Options{}, out, stderr), io.Writer(out))
+	assert.Equal(t, eventOutput(&Options{OutputToStderr: true}, out, stderr), io.Writer(stderr))
+}
+
+func TestRemapExitCode(t *testing.T) {
+	failed := &exitError{code: 1, reason: "tests failed"}
+	internal := errors.New("failed to run go test")
+
+	opts := &Options{}
+	assert.Equal(t, remapExitCode(opts, failed), error(failed))
+	assert.Equal(t, remapExitCode(opts, internal), internal)
+	assert.Equal(t, InternalErrorExitCode(remapExitCode(opts, internal)), 3)
+
+	opts = &Options{ExitCodeOnFailure: 10, ExitCodeOnInternalError: 20}
+	assert.NilError(t, remapExitCode(opts, nil))
+	err := remapExitCode(opts, failed)
+	assert.Assert(t, IsExitError(err))
+	assert.Equal(t, ExitCodeWithDefault(err), 10)
+	err = remapExitCode(opts, internal)
+	assert.Assert(t, !IsExitError(err))
+	assert.Equal(t, InternalErrorExitCode(err), 20)
+	assert.Error(t, err, "failed to run go test")
+
+	interrupted := &exitError{code: 130, reason: "interrupted", stopped: true, signal: os.Interrupt}
+	assert.Equal(t, remapExitCode(opts, interrupted), error(interrupted))
+	report := &reportError{errs: []error{errors.New("failed to write junit file")}}
+	err = remapExitCode(opts, report)
+	assert.Equal(t, err, error(report))
+	assert.Equal(t, InternalErrorExitCode(err), 4)
+}
+
+func TestSummarySections(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		opts     Options
+		expected testjson.Summary
+	}{
+		{
+			name:     "default",
+			expected: testjson.SummarizeAll,
+		},
+		{
+			name:     "no-summary",
+			opts:     Options{NoSummary: []string{"skipped", "skipped"}},
+			expected: testjson.SummarizeAll &^ testjson.SummarizeSkipped,
+		},
+		{
+			name:     "summary",
+			opts:     Options{Summary: []string{"failed"}},
+			expected: testjson.SummarizeFailed,
+		},
+		{
+			name: "summary and no-summary",
+			opts: Options{
+				Summary:   []string{"failed", "errors"},
+				NoSummary: []string{"errors", "skipped"},
+			},
+			expected: testjson.SummarizeFailed,
+		},
+		{
+			name:     "summary panic",
+			opts:     Options{Summary: []string{"failed", "panic"}},
+			expected: testjson.SummarizeFailed | testjson.SummarizePanics,
+		},
+		{
+			name:     "no-summary all",
+			opts:     Options{NoSummary: []string{"all"}},
+			expected: 0,
+		},
+		{
+			name:     "no-summary output",
+			opts:     Options{NoSummary: []string{"output", "errors"}},
+			expected: testjson.SummarizeAll&^testjson.SummarizeErrors | testjson.SummarizeHideOutput,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, summarySections(&tc.opts), tc.expected)
+		})
+	}
+}
+
+func TestValidateSummarySections(t *testing.T) {
+	assert.NilError(t, validateSummarySections([]string{"failed", "errors"}))
+	err := validateSummarySections([]string{"failed", "slowest"})
+	assert.ErrorContains(t, err, `invalid --summary "slowest"`)
+}
+
+func TestNoSummaryValue(t *testing.T) {
+	var value []string
+	flag := (*noSummaryValue)(&value)
+	assert.NilError(t, flag.Set("skipped, output"))
+	assert.NilError(t, flag.Set("all"))
+	assert.DeepEqual(t, value, []string{"skipped", "output", "all"})
+
+	assert.NilError(t, validateNoSummary(value))
+
+	assert.NilError(t, flag.Set("failed,skiped"))
+	err := validateOpts(&Options{NoSummary: value})
+	assert.ErrorContains(t, err, `invalid --no-summary "skiped"`)
+}
+
+func TestRun_SummaryPositionTop(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:          "dots",
+		RawCommand:      true,
+		Args:            []string{"cat", "testdata/skipped.json"},
+		SummaryPosition: "top",
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	done := strings.Index(out.String(), "DONE 2 tests, 1 skipped")
+	skipped := strings.Index(out.String(), "=== Skipped")
+	assert.Assert(t, done >= 0 && skipped > done, out.String())
+
+	err := validateSummaryPosition("middle")
+	assert.ErrorContains(t, err, "invalid --summary-position value middle")
+}
+
+func TestRun_StderrNoise(t *testing.T) {
+	defer patchNoColor(true)()
+	script := `echo "go: downloading example.com/dep v1.0.0" >&2
+head -n 3 testdata/skipped.json
+echo "go: warning: ignoring symlink /src/link"
+echo "go: warning: GOPATH set to GOROOT" >&2
+tail -n +4 testdata/skipped.json`
+	opts := Options{
+		Format:     "short",
+		RawCommand: true,
+		Args:       []string{"sh", "-c", script},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 2 tests, 1 skipped, 3 errors"))
+	assert.Assert(t, cmp.Contains(out.String(), "go: warning: ignoring symlink /src/link\n"))
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// openMergedFiles returns a reader with the test2json events from all of the
+// files, so that the files from test runs on different machines, like the
+// shards of a CI job, can be read as a single run. A warning is printed when
+// the same package is in more than one file, because the results of the
+// package will be combined.
+func openMergedFiles(filenames []string) (io.ReadCloser, error) {
+	if len(filenames) == 0 {
+		return nil, errors.New("--merge-json requires the files to merge as arguments")
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(mergeFiles(writer, filenames)) // nolint: errcheck
+	}()
+	return reader, nil
+}
+
+func mergeFiles(out io.Writer, filenames []string) error {
+	seen := make(map[string]string)
+	for _, filename := range filenames {
+		if err := mergeFile(out, filename, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFile copies the lines of the file to out. seen maps the name of each
+// package to the first file which included the package.
+func mergeFile(out io.Writer, filename string, seen map[string]string) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file to merge")
+	}
+	defer in.Close() // nolint: errcheck
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var event struct{ Package string }
+		if json.Unmarshal(line, &event) == nil && event.Package != "" {
+			switch first, ok := seen[event.Package]; {
+			case !ok:
+				seen[event.Package] = filename
+			case first != filename:
+				log.Warnf("package %s is in both %s and %s, the results will be combined",
+					event.Package, first, filename)
+				// warn once for each file which repeats the package
+				seen[event.Package] = filename
+			}
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return errors.Wrapf(scanner.Err(), "failed to read %s", filename)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_MergeJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-merge")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	shard := filepath.Join(dir, "shard.json")
+	other := `{"Action":"run","Package":"example.com/other","Test":"TestOther"}
+{"Action":"fail","Package":"example.com/other","Test":"TestOther"}
+{"Action":"fail","Package":"example.com/other"}`
+	assert.NilError(t, ioutil.WriteFile(shard, []byte(other), 0644))
+
+	opts := Options{
+		Format:    "short",
+		MergeJSON: true,
+		Args:      []string{"testdata/skipped.json", shard},
+	}
+	out := new(bytes.Buffer)
+	err = Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 3 tests, 1 skipped, 1 failure"))
+}
+
+func TestMergeFiles_DuplicatePackage(t *testing.T) {
+	logs := new(bytes.Buffer)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	out := new(bytes.Buffer)
+	err := mergeFiles(out, []string{"testdata/skipped.json", "testdata/skipped.json"})
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(out.String(), "\n"), 10)
+	assert.Equal(t, strings.Count(logs.String(), "package example.com/pkg is in both"), 0)
+
+	err = mergeFiles(out, []string{"testdata/skipped.json", "./testdata/skipped.json"})
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(logs.String(), "package example.com/pkg is in both"), 1)
+}
+
+func TestRun_MergeJSONNoFiles(t *testing.T) {
+	err := Run(context.Background(), Options{Format: "short", MergeJSON: true}, ioutil.Discard)
+	assert.Error(t, err, "--merge-json requires the files to merge as arguments")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"io"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// ExecutionObserver is called with the Execution once all the tests have run,
+// after the summary is printed. Observers can be used to write reports, or to
+// upload the results of the run. Additional observers are set with
+// Options.Observers.
+type ExecutionObserver interface {
+	Observe(execution *testjson.Execution) error
+}
+
+// ExecutionObserverFunc is an ExecutionObserver implemented by a function.
+type ExecutionObserverFunc func(execution *testjson.Execution) error
+
+// Observe calls f with execution.
+func (f ExecutionObserverFunc) Observe(execution *testjson.Execution) error {
+	return f(execution)
+}
+
+// executionObservers returns the built-in observers which write the JUnit XML
+// file, the JSON summary, the Markdown summary, the trace file, and the rerun
+// fails report, followed by the observers from opts.
+func executionObservers(
+	opts *Options,
+	out io.Writer,
+	reruns rerunAttempts,
+) []ExecutionObserver {
+	observers := []ExecutionObserver{
+		ExecutionObserverFunc(func(execution *testjson.Execution) error {
+			return writeJUnitFile(opts, out, execution)
+		}),
+		ExecutionObserverFunc(func(execution *testjson.Execution) error {
+			return writeJSONSummary(opts.JSONSummaryFile, execution)
+		}),
+		ExecutionObserverFunc(func(execution *testjson.Execution) error {
+			return writeMarkdownSummary(opts.MarkdownSummaryFile, execution)
+		}),
+		ExecutionObserverFunc(func(execution *testjson.Execution) error {
+			return writeTraceFile(opts.TraceFile, execution)
+		}),
+		ExecutionObserverFunc(func(execution *testjson.Execution) error {
+			return writeRerunReport(opts.RerunFailsReportFile, execution, reruns)
+		}),
+	}
+	return append(observers, opts.Observers...)
+}
+
+// notifyObservers calls each observer in order. An error from an observer does
+// not stop the other observers, so that one report which can not be written
+// does not prevent the others. The errors are returned as a reportError.
+func notifyObservers(
+	opts *Options,
+	out io.Writer,
+	execution *testjson.Execution,
+	reruns rerunAttempts,
+) error {
+	var failed []error
+	for _, observer := range executionObservers(opts, out, reruns) {
+		if err := observer.Observe(execution); err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &reportError{errs: failed}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// readPackagesFile returns the packages listed in the --packages-from-file.
+// Each line of the file is a package. Text after a # is a comment, and empty
+// lines are ignored.
+func readPackagesFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open --packages-from-file")
+	}
+	defer file.Close() // nolint: errcheck
+
+	var packages []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		packages = append(packages, strings.Fields(line)...)
+	}
+	return packages, errors.Wrapf(scanner.Err(), "failed to read %s", filename)
+}
+
+// addPackagesFromFile adds the packages from the --packages-from-file to the
+// packages from --packages. The packages are copied, so that the slice of the
+// caller is not modified.
+func addPackagesFromFile(opts *Options) error {
+	if opts.PackagesFromFile == "" {
+		return nil
+	}
+	packages, err := readPackagesFile(opts.PackagesFromFile)
+	if err != nil {
+		return err
+	}
+	opts.Packages = append(append([]string(nil), opts.Packages...), packages...)
+	return nil
+}
+
+// noPackagesToTest returns true when the --packages-from-file does not list
+// any packages, and no other packages were selected by --packages,
+// TEST_DIRECTORY, or the go test args. The default packages are not tested in
+// that case, because the file is usually a list of affected packages.
+func noPackagesToTest(opts *Options) bool {
+	if opts.PackagesFromFile == "" || len(testPackages(opts, "")) > 0 {
+		return false
+	}
+	return !hasPackageArgs(append(append([]string(nil), opts.DefaultArgs...), opts.Args...))
+}
+
+// runWithoutPackages finishes a run which did not run go test, because there
+// are no packages to test. The summary, the reports, and --post-run-command
+// are handled in the same way as a run with no tests.
+func runWithoutPackages(opts *Options, out io.Writer) error {
+	log.Warnf("%s does not list any packages, no tests were run", opts.PackagesFromFile)
+	handler, err := newEventHandler(opts, out, stderrOutput(opts), out)
+	if err != nil {
+		return err
+	}
+	defer handler.Close() // nolint: errcheck
+	return finishRun(opts, out, handler, testjson.NewExecution(), nil)
+}
+
+// goTestBoolFlags are the go test and build flags which do not have a value.
+var goTestBoolFlags = map[string]bool{
+	"a": true, "asan": true, "benchmem": true, "cover": true, "failfast": true,
+	"fullpath": true, "json": true, "linkshared": true, "modcacherw": true,
+	"msan": true, "n": true, "race": true, "short": true, "trimpath": true,
+	"v": true, "work": true, "x": true,
+}
+
+// hasPackageArgs returns true if the go test args include a package. Flags
+// which are not known to be boolean are expected to be followed by their
+// value, unless the value is part of the flag. Args after -args are passed to
+// the test binary.
+func hasPackageArgs(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		flag := strings.TrimPrefix(trimFlagDashes(args[i]), "test.")
+		switch {
+		case flag == "args":
+			return false
+		case !strings.HasPrefix(args[i], "-"):
+			return true
+		case strings.Contains(flag, "=") || goTestBoolFlags[flag]:
+		default:
+			i++
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_PackagesFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-packages")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "packages.txt")
+	content := "# affected packages\n./io/...\n\n  ./net # changed in this branch\n"
+	assert.NilError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	opts := Options{
+		Format:           "short",
+		DryRun:           true,
+		Packages:         []string{"./cmd"},
+		PackagesFromFile: filename,
+		Args:             []string{"-count=1"},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Equal(t, out.String(), "go test -json -count=1 ./cmd ./io/... ./net\n")
+}
+
+func TestRun_PackagesFromFileEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-packages")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "packages.txt")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("# no packages\n"), 0644))
+
+	opts := Options{
+		Format:           "short",
+		GoBinary:         "/missing/go",
+		PackagesFromFile: filename,
+		Args:             []string{"-count", "1", "-run=TestA"},
+		JSONFile:         filepath.Join(dir, "out.json"),
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Assert(t, cmp.Contains(out.String(), "DONE 0 tests"))
+	_, err = os.Stat(opts.JSONFile)
+	assert.NilError(t, err)
+
+	opts.NoTestsFail = true
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+}
+
+func TestRun_PackagesFromFileEmptyWithPackageArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-packages")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "packages.txt")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("\n"), 0644))
+
+	opts := Options{
+		Format:           "short",
+		DryRun:           true,
+		PackagesFromFile: filename,
+		Args:             []string{"-run", "TestA", "./foo"},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Equal(t, out.String(), "go test -json -run TestA ./foo\n")
+}
+
+func TestAddPackagesFromFile_CopiesPackages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-packages")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "packages.txt")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("./b\n"), 0644))
+
+	packages := make([]string, 1, 2)
+	packages[0] = "./a"
+	opts := &Options{Packages: packages, PackagesFromFile: filename}
+	assert.NilError(t, addPackagesFromFile(opts))
+	assert.DeepEqual(t, opts.Packages, []string{"./a", "./b"})
+	assert.DeepEqual(t, packages[:2], []string{"./a", ""})
+}
+
+func TestHasPackageArgs(t *testing.T) {
+	var testcases = []struct {
+		args     []string
+		expected bool
+	}{
+		{args: nil},
+		{args: []string{"-count=1", "-v", "-race"}},
+		{args: []string{"-run", "TestA", "-timeout", "5m"}},
+		{args: []string{"-run", "TestA", "./..."}, expected: true},
+		{args: []string{"-v", "./pkg"}, expected: true},
+		{args: []string{"-tags=a", "example.com/pkg"}, expected: true},
+		{args: []string{"-count=1", "-args", "./pkg"}},
+	}
+	for _, tc := range testcases {
+		assert.Equal(t, hasPackageArgs(tc.args), tc.expected, tc.args)
+	}
+}
+
+func TestRun_PackagesFromFileMissing(t *testing.T) {
+	opts := Options{Format: "short", PackagesFromFile: "/missing/packages.txt"}
+	err := Run(context.Background(), opts, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "failed to open --packages-from-file")
+}
+
+func TestValidateRawCommandOpts_PackagesFromFile(t *testing.T) {
+	opts := &Options{RawCommand: true, PackagesFromFile: "packages.txt"}
+	err := validateRawCommandOpts(opts)
+	assert.Error(t, err, "--packages-from-file can not be used with --raw-command")
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// parallelism is the value of the -p and -parallel flags passed to go test.
+// An empty value is the default value of the flag.
+type parallelism struct {
+	p        string
+	parallel string
+}
+
+// parseParallelism returns the -p and -parallel flags from the go test args,
+// and false if neither flag was set. Flags after -args are passed to the test
+// binary, and are ignored.
+func parseParallelism(args []string) (parallelism, bool) {
+	var result parallelism
+	var found bool
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-args" || args[i] == "--args" {
+			break
+		}
+		name, value, hasValue := splitFlagArg(args[i])
+		if name != "p" && name != "parallel" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		found = true
+		if name == "p" {
+			result.p = value
+		} else {
+			result.parallel = value
+		}
+	}
+	return result, found
+}
+
+// splitFlagArg returns the name of a flag, without the leading dashes, and the
+// value when the arg is in the form -name=value. An empty name is returned if
+// the arg is not a flag.
+func splitFlagArg(arg string) (string, string, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", "", false
+	}
+	name := strings.TrimLeft(arg, "-")
+	if index := strings.Index(name, "="); index >= 0 {
+		return name[:index], name[index+1:], true
+	}
+	return name, "", false
+}
+
+// goTestParallelism returns the -p and -parallel flags used by the go test
+// command, from GOFLAGS and from the go test args, including the default args.
+// The go test args take precedence over GOFLAGS.
+func goTestParallelism(opts *Options) (parallelism, bool) {
+	result, found := parseParallelism(strings.Fields(goFlags(opts)))
+	fromArgs, foundInArgs := parseParallelism(goTestCmdArgs(opts, rerunOpts{}))
+	if fromArgs.p != "" {
+		result.p = fromArgs.p
+	}
+	if fromArgs.parallel != "" {
+		result.parallel = fromArgs.parallel
+	}
+	return result, found || foundInArgs
+}
+
+// goFlags returns the value of GOFLAGS used by the go test command. A value
+// set with --env replaces the value from the environment.
+func goFlags(opts *Options) string {
+	value := os.Getenv("GOFLAGS")
+	for _, env := range opts.Env {
+		if strings.HasPrefix(env, "GOFLAGS=") {
+			value = strings.TrimPrefix(env, "GOFLAGS=")
+		}
+	}
+	return value
+}
+
+// parallelismEnabled returns true if the go test command sets -p or -parallel,
+// in GOFLAGS or in the go test args. The args of a --raw-command are not go
+// test args, and are not checked.
+func parallelismEnabled(opts *Options) bool {
+	switch {
+	case isRawCommand(opts), opts.RawFromFile != "", opts.JUnitFromFile != "", opts.MergeJSON:
+		return false
+	}
+	_, ok := goTestParallelism(opts)
+	return ok
+}
+
+// printParallelism prints the effective value of -p and -parallel, and the
+// ratio of the sum of the test time to the wall time, which shows how well the
+// tests ran in parallel.
+func printParallelism(out io.Writer, opts *Options, exec *testjson.Execution) {
+	values, _ := goTestParallelism(opts)
+	line := fmt.Sprintf("\nParallelism: -p=%s -parallel=%s",
+		flagValueOrDefault(values.p), flagValueOrDefault(values.parallel))
+	if elapsed := exec.Elapsed(); elapsed > 0 && exec.TestTime() > 0 {
+		ratio := exec.TestTime().Seconds() / elapsed.Seconds()
+		line += fmt.Sprintf(", sum of test time is %.1fx the wall time", ratio)
+	}
+	fmt.Fprintln(out, line)
+}
+
+// flagValueOrDefault returns the value, or the default value of -p and
+// -parallel, which is GOMAXPROCS, when the value is empty.
+func flagValueOrDefault(value string) string {
+	if value == "" {
+		return strconv.Itoa(runtime.GOMAXPROCS(0)) + " (default)"
+	}
+	return value
+}
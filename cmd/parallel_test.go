@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestParseParallelism(t *testing.T) {
+	var testcases = []struct {
+		args     []string
+		expected parallelism
+		found    bool
+	}{
+		{args: []string{"./..."}},
+		{args: []string{"-p", "4", "./..."}, expected: parallelism{p: "4"}, found: true},
+		{
+			args:     []string{"-p=2", "--parallel", "8", "./..."},
+			expected: parallelism{p: "2", parallel: "8"},
+			found:    true,
+		},
+		{args: []string{"-run=TestP", "./...", "-args", "-p", "4"}},
+	}
+	for _, tc := range testcases {
+		actual, found := parseParallelism(tc.args)
+		assert.Equal(t, actual, tc.expected, tc.args)
+		assert.Equal(t, found, tc.found, tc.args)
+	}
+}
+
+func TestParallelismEnabled(t *testing.T) {
+	assert.Assert(t, parallelismEnabled(&Options{Args: []string{"-parallel=4"}}))
+	assert.Assert(t, !parallelismEnabled(&Options{Args: []string{"./..."}}))
+	opts := &Options{RawCommand: true, Args: []string{"./script", "-p", "4"}}
+	assert.Assert(t, !parallelismEnabled(opts))
+	assert.Assert(t, parallelismEnabled(&Options{DefaultArgs: []string{"-p=2"}}))
+	assert.Assert(t, parallelismEnabled(&Options{Env: []string{"GOFLAGS=-mod=mod -p=2"}}))
+}
+
+func TestGoTestParallelism(t *testing.T) {
+	opts := &Options{
+		Env:         []string{"GOFLAGS=-p=2 -parallel=3"},
+		DefaultArgs: []string{"-parallel=6"},
+		Args:        []string{"-run=TestA", "./..."},
+	}
+	values, found := goTestParallelism(opts)
+	assert.Assert(t, found)
+	assert.Equal(t, values, parallelism{p: "2", parallel: "6"})
+
+	opts.Args = []string{"-p", "8", "./..."}
+	values, _ = goTestParallelism(opts)
+	assert.Equal(t, values, parallelism{p: "8", parallel: "6"})
+}
+
+func TestPrintParallelism(t *testing.T) {
+	out := new(bytes.Buffer)
+	opts := &Options{Args: []string{"-p", "4", "-parallel=8"}}
+	printParallelism(out, opts, testjson.NewExecution())
+	assert.Assert(t, cmp.Contains(out.String(), "\nParallelism: -p=4 -parallel=8"))
+}
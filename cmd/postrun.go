@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// postRunHook runs the --post-run-command after the test run is complete. The
+// results of the test run are passed to the command as environment variables.
+func postRunHook(opts *Options, execution *testjson.Execution, exitErr error) error {
+	command := opts.PostRunHookCmd.Value()
+	if len(command) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), postRunHookEnv(opts, execution, exitErr)...)
+	return cmd.Run()
+}
+
+func postRunHookEnv(opts *Options, execution *testjson.Execution, exitErr error) []string {
+	result := "pass"
+	if exitErr != nil {
+		result = "fail"
+	}
+	return []string{
+		"GOTESTSUM_FORMAT=" + opts.Format,
+		"GOTESTSUM_JSONFILE=" + opts.JSONFile,
+		"GOTESTSUM_JUNITFILE=" + opts.JUnitFile,
+		"TESTS_TOTAL=" + strconv.Itoa(execution.Total()),
+		"TESTS_FAILED=" + strconv.Itoa(len(execution.Failed())),
+		"TESTS_SKIPPED=" + strconv.Itoa(len(execution.Skipped())),
+		"TESTS_ERRORS=" + strconv.Itoa(len(execution.Errors())),
+		"TESTS_RESULT=" + result,
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestPostRunHookEnv(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"pkg","Test":"TestA"}
+{"Action":"pass","Package":"pkg","Test":"TestA"}
+{"Action":"run","Package":"pkg","Test":"TestB"}
+{"Action":"fail","Package":"pkg","Test":"TestB"}
+{"Action":"run","Package":"pkg","Test":"TestC"}
+{"Action":"skip","Package":"pkg","Test":"TestC"}
+{"Action":"fail","Package":"pkg"}
+`),
+		Stderr:  strings.NewReader("an error\n"),
+		Handler: noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	opts := &Options{Format: "short", JSONFile: "out.json", JUnitFile: "junit.xml"}
+	env := postRunHookEnv(opts, exec, &exitError{code: 1})
+	expected := []string{
+		"GOTESTSUM_FORMAT=short",
+		"GOTESTSUM_JSONFILE=out.json",
+		"GOTESTSUM_JUNITFILE=junit.xml",
+		"TESTS_TOTAL=3",
+		"TESTS_FAILED=1",
+		"TESTS_SKIPPED=1",
+		"TESTS_ERRORS=1",
+		"TESTS_RESULT=fail",
+	}
+	assert.DeepEqual(t, env, expected)
+
+	env = postRunHookEnv(opts, exec, nil)
+	assert.Equal(t, env[len(env)-1], "TESTS_RESULT=pass")
+}
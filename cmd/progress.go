@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// defaultProgressWidth is the width of the terminal used when the width can
+// not be read from the terminal.
+const defaultProgressWidth = 80
+
+// progressEnabled returns true if a progress status line should be printed.
+// The status line is only printed with the dots format, or with
+// --collapse-passing-packages, and only when out is a terminal, so that the
+// control characters are not written to log files. It is not printed when
+// --jsonfile=- writes the events to the same output.
+func progressEnabled(opts *Options, out io.Writer) bool {
+	dotsProgress := opts.Progress && opts.Format == "dots"
+	switch {
+	case !dotsProgress && !opts.CollapsePassingPackages:
+		return false
+	case opts.JSONFile == "-" && !opts.OutputToStderr:
+		// the status line would be printed between the JSON events
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// progressWriter is an io.Writer which prints a status line at the end of the
+// output. The status line is removed before every write, and is printed again
+// by calling draw.
+type progressWriter struct {
+	out   io.Writer
+	width int
+	// column is the number of characters printed since the last newline.
+	column int
+	// status is the status line currently printed, or empty if there is none.
+	status string
+}
+
+func newProgressWriter(out io.Writer, width int) *progressWriter {
+	if width <= 0 {
+		width = defaultProgressWidth
+	}
+	return &progressWriter{out: out, width: width}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.clear(); err != nil {
+		return 0, err
+	}
+	w.column = nextColumn(w.column, p)
+	return w.out.Write(p)
+}
+
+// clear removes the status line by moving the cursor back to the end of the
+// output and erasing the rest of the line.
+func (w *progressWriter) clear() error {
+	if w.status == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w.out, "\033[%dD\033[K", utf8.RuneCountInString(w.status))
+	w.status = ""
+	return err
+}
+
+// draw prints status after the output. The status is not printed if it does
+// not fit on the rest of the line, because moving the cursor back does not
+// work across wrapped lines.
+func (w *progressWriter) draw(status string) error {
+	status = "  " + status
+	if status == w.status {
+		return nil
+	}
+	if err := w.clear(); err != nil {
+		return err
+	}
+	if w.column%w.width+utf8.RuneCountInString(status) >= w.width {
+		return nil
+	}
+	w.status = status
+	_, err := io.WriteString(w.out, status)
+	return err
+}
+
+// nextColumn returns the column of the cursor after p is printed at column.
+// ANSI escape sequences, like the ones used for color, are not counted.
+func nextColumn(column int, p []byte) int {
+	var escape bool
+	for _, r := range string(p) {
+		switch {
+		case escape:
+			escape = !isEscapeEnd(r)
+		case r == '\033':
+			escape = true
+		case r == '\n' || r == '\r':
+			column = 0
+		default:
+			column++
+		}
+	}
+	return column
+}
+
+func isEscapeEnd(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// progressStatus returns the status line with the counts of the tests which
+// have completed so far.
+func progressStatus(exec *testjson.Execution) string {
+	var passed, failed, skipped int
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		passed += len(pkg.Passed)
+		failed += len(pkg.Failed)
+		skipped += len(pkg.Skipped)
+	}
+	return fmt.Sprintf("%d passed, %d failed, %d skipped", passed, failed, skipped)
+}
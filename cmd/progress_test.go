@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestProgressWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	w := newProgressWriter(out, 40)
+
+	_, err := w.Write([]byte("[pkg]··"))
+	assert.NilError(t, err)
+	assert.NilError(t, w.draw("2 passed"))
+	assert.NilError(t, w.draw("2 passed"))
+	_, err = w.Write([]byte("·"))
+	assert.NilError(t, err)
+	assert.NilError(t, w.draw("3 passed"))
+	_, err = w.Write([]byte("\nDONE\n"))
+	assert.NilError(t, err)
+
+	expected := "[pkg]··  2 passed\033[10D\033[K·  3 passed\033[10D\033[K\nDONE\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestProgressWriterStatusDoesNotFit(t *testing.T) {
+	out := new(bytes.Buffer)
+	w := newProgressWriter(out, 12)
+
+	_, err := w.Write([]byte("[pkg]·····"))
+	assert.NilError(t, err)
+	assert.NilError(t, w.draw("5 passed"))
+	assert.Equal(t, out.String(), "[pkg]·····")
+}
+
+func TestNextColumn(t *testing.T) {
+	var testcases = []struct {
+		column   int
+		raw      string
+		expected int
+	}{
+		{raw: "", expected: 0},
+		{column: 3, raw: "··", expected: 5},
+		{column: 3, raw: "done\n[pkg]", expected: 5},
+		{raw: "\033[32m·\033[0m", expected: 1},
+		{column: 7, raw: "\r", expected: 0},
+	}
+	for _, tc := range testcases {
+		assert.Equal(t, nextColumn(tc.column, []byte(tc.raw)), tc.expected, tc.raw)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// maxCheckedLine is the number of bytes of each line of output which are kept
+// to check if the line is a test2json event.
+const maxCheckedLine = 64 * 1024
+
+// rawOutputChecker is an io.Reader which checks that the output from a
+// --raw-command includes test2json events. Lines which are not JSON, like the
+// FAIL line of a package which failed to build, are handled as stderr by
+// testjson.ScanTestOutput, so they are passed through. The read fails when the
+// output ends without any test2json event. Without this check a command which
+// does not print -json output does not report any tests.
+type rawOutputChecker struct {
+	reader io.Reader
+	line   []byte
+	// first is the first line which was not a test2json event.
+	first []byte
+	found bool
+}
+
+func newRawOutputChecker(reader io.Reader) *rawOutputChecker {
+	return &rawOutputChecker{reader: reader}
+}
+
+func (c *rawOutputChecker) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	if c.found {
+		return n, err
+	}
+	for _, b := range p[:n] {
+		if b == '\n' {
+			c.checkLine()
+			continue
+		}
+		if len(c.line) < maxCheckedLine {
+			c.line = append(c.line, b)
+		}
+	}
+	if err != io.EOF {
+		return n, err
+	}
+	c.checkLine()
+	if !c.found && c.first != nil {
+		return n, errNotJSONOutput(c.first)
+	}
+	return n, err
+}
+
+func (c *rawOutputChecker) checkLine() {
+	line := bytes.TrimSpace(c.line)
+	c.line = c.line[:0]
+	switch {
+	case c.found || len(line) == 0:
+	case line[0] == '{' && json.Valid(line):
+		c.found = true
+	case c.first == nil:
+		c.first = append([]byte(nil), line...)
+	}
+}
+
+func errNotJSONOutput(line []byte) error {
+	if len(line) > 80 {
+		line = append(line[:80:80], "..."...)
+	}
+	return errors.Errorf("the output of --raw-command is not go test -json output, "+
+		"the first line was %q. The command must print test2json events, "+
+		"for example by running 'go test -json'", line)
+}
+
+func validateRawCommandOpts(opts *Options) error {
+	switch {
+	case isRawCommand(opts) && opts.PackagesFromFile != "":
+		return errors.New("--packages-from-file can not be used with --raw-command")
+	case isRawCommand(opts) && len(opts.Packages) > 0:
+		return errors.New("--packages can not be used with --raw-command")
+	case opts.RawCommandShell && len(opts.Args) != 1:
+		return errors.Errorf("--raw-command-shell requires a single argument with the "+
+			"shell script, got %d arguments", len(opts.Args))
+	case opts.RawCommandJSONStderr && !isRawCommand(opts):
+		return errors.New("--raw-command-json-stderr requires --raw-command")
+	}
+	return nil
+}
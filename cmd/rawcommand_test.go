@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gotest.tools/assert"
+)
+
+func TestRawOutputChecker(t *testing.T) {
+	raw := "\n" + `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}` + "\nnot json\n"
+	reader := newRawOutputChecker(iotest.OneByteReader(strings.NewReader(raw)))
+	out, err := ioutil.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), raw)
+}
+
+func TestRawOutputCheckerNotJSON(t *testing.T) {
+	reader := newRawOutputChecker(strings.NewReader("=== RUN   TestOne\n--- PASS: TestOne\n"))
+	_, err := ioutil.ReadAll(reader)
+	assert.ErrorContains(t, err, "the output of --raw-command is not go test -json output")
+	assert.ErrorContains(t, err, `"=== RUN   TestOne"`)
+}
+
+func TestRawOutputCheckerBuildFailed(t *testing.T) {
+	raw := "# example.com/pkg\nfoo.go:3:1: syntax error\nFAIL\texample.com/pkg [build failed]\n" +
+		`{"Action":"pass","Package":"example.com/other","Elapsed":0.1}` + "\n"
+	reader := newRawOutputChecker(iotest.OneByteReader(strings.NewReader(raw)))
+	out, err := ioutil.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), raw)
+}
+
+func TestRawOutputCheckerNoTrailingNewline(t *testing.T) {
+	raw := "FAIL\texample.com/pkg [build failed]\n" + `{"Action":"fail","Package":"example.com/pkg"}`
+	out, err := ioutil.ReadAll(newRawOutputChecker(strings.NewReader(raw)))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), raw)
+}
+
+func TestRawOutputCheckerEmpty(t *testing.T) {
+	out, err := ioutil.ReadAll(newRawOutputChecker(strings.NewReader("")))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "")
+}
+
+func TestValidateRawCommandOpts_ShellArgs(t *testing.T) {
+	opts := &Options{RawCommandShell: true, Args: []string{"go", "test", "-run", "Test A"}}
+	err := validateRawCommandOpts(opts)
+	assert.ErrorContains(t, err, "--raw-command-shell requires a single argument")
+
+	opts.Args = []string{"go test -json ./... | tee out.json"}
+	assert.NilError(t, validateRawCommandOpts(opts))
+}
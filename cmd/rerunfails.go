@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// rerunOpts replaces the packages of the go test command with a single
+// package, and optionally limits the tests which are run with a -run flag.
+type rerunOpts struct {
+	runFlag string
+	pkg     string
+}
+
+// Args returns the go test arguments used to run the tests of a single
+// package again.
+func (o rerunOpts) Args() []string {
+	if o.runFlag == "" {
+		return []string{o.pkg}
+	}
+	return []string{o.runFlag, o.pkg}
+}
+
+func validateRerunOpts(opts *Options) error {
+	if opts.RerunFailsMaxAttempts == 0 {
+		return validateNoRerunOpts(opts)
+	}
+	switch {
+	case opts.RerunFailsMaxAttempts < 0:
+		return errors.New("--rerun-fails must be a positive number")
+	case opts.RerunFailsMaxFailures < 0:
+		return errors.New("--rerun-fails-max-failures must be a positive number")
+	case isRawCommand(opts):
+		return errors.New("--rerun-fails can not be used with --raw-command")
+	case opts.RawFromFile != "":
+		return errors.New("--rerun-fails can not be used with --raw-from-file")
+	case opts.JUnitFromFile != "":
+		return errors.New("--rerun-fails can not be used with --junit-from-file")
+	case opts.MergeJSON:
+		return errors.New("--rerun-fails can not be used with --merge-json")
+	case len(opts.Args) > 0 && len(testPackages(opts, "")) == 0:
+		return errors.New("when go test args are used with --rerun-fails " +
+			"the list of packages to test must be set with --packages or TEST_DIRECTORY")
+	}
+	_, err := regexp.Compile(opts.RerunFailsPackages)
+	return errors.Wrap(err, "invalid --rerun-fails-packages")
+}
+
+// validateNoRerunOpts returns an error if a flag which requires --rerun-fails
+// is set without it.
+func validateNoRerunOpts(opts *Options) error {
+	switch {
+	case opts.RerunFailsReportFile != "":
+		return errors.New("--rerun-fails-report requires --rerun-fails")
+	case opts.RerunFailsPackages != "":
+		return errors.New("--rerun-fails-packages requires --rerun-fails")
+	case opts.RerunFailsMaxFailures != 0:
+		return errors.New("--rerun-fails-max-failures requires --rerun-fails")
+	}
+	return nil
+}
+
+// rerunFailed runs the failed tests of each package again, until either all
+// the tests pass, or the maximum number of attempts is reached. Tests are
+// not rerun if the previous run failed for some other reason, like a build
+// failure, a package-level failure in init() or TestMain, a test failed in a
+// package which does not match --rerun-fails-packages, or more tests failed
+// than --rerun-fails-max-failures.
+//
+// The run number of the last run of each test which was rerun is recorded in
+// reruns.
+func rerunFailed(
+	ctx context.Context,
+	opts *Options,
+	cfg testjson.ScanConfig,
+	exitErr error,
+	reruns rerunAttempts,
+) error {
+	pkgFilter, err := regexp.Compile(opts.RerunFailsPackages)
+	if err != nil {
+		return err
+	}
+	reason := rerunBlocker(cfg.Execution, exitErr, pkgFilter, opts.RerunFailsMaxFailures)
+	if reason != "" {
+		log.Warnf("not rerunning failed tests: %s", reason)
+		return exitErr
+	}
+
+	failed := newFailureRecorder(cfg.Handler, 1)
+	for _, tc := range cfg.Execution.Failed() {
+		failed.add(tc.Package, tc.Test)
+	}
+
+	for failed.attempt <= opts.RerunFailsMaxAttempts && failed.count() > 0 {
+		next := newFailureRecorder(cfg.Handler, failed.attempt+1)
+		exitErr = nil
+		for _, pkg := range failed.packages() {
+			cfg.Handler = next
+			reruns.add(pkg, failed.tests[pkg], next.attempt)
+			err := rerunPackage(ctx, opts, cfg, pkg, failed.tests[pkg])
+			switch {
+			case err == nil:
+			case IsExitError(err):
+				exitErr = err
+			default:
+				return err
+			}
+		}
+		failed = next
+	}
+	return exitErr
+}
+
+func rerunBlocker(
+	exec *testjson.Execution,
+	exitErr error,
+	pkgFilter *regexp.Regexp,
+	maxFailures int,
+) string {
+	if code := ExitCodeWithDefault(exitErr); code != 1 {
+		return fmt.Sprintf("go test exited with code %d", code)
+	}
+	if count := countRootTests(exec.Failed()); maxFailures > 0 && count > maxFailures {
+		return fmt.Sprintf("%d tests failed, more than --rerun-fails-max-failures=%d",
+			count, maxFailures)
+	}
+	if len(exec.Errors()) > 0 {
+		return "the previous run had errors"
+	}
+	for _, name := range exec.Packages() {
+		if exec.Package(name).TestMainFailed() {
+			return fmt.Sprintf("package %s failed without a test failure", name)
+		}
+	}
+	for _, tc := range exec.Failed() {
+		if !pkgFilter.MatchString(tc.Package) {
+			return fmt.Sprintf("package %s does not match --rerun-fails-packages", tc.Package)
+		}
+	}
+	return ""
+}
+
+// countRootTests returns the number of test cases which are not subtests.
+func countRootTests(testCases []testjson.TestCase) int {
+	var count int
+	for _, tc := range testCases {
+		if isRootTest(tc.Test) {
+			count++
+		}
+	}
+	return count
+}
+
+func rerunPackage(
+	ctx context.Context,
+	opts *Options,
+	cfg testjson.ScanConfig,
+	pkg string,
+	tests []string,
+) error {
+	args := goTestCmdArgs(opts, rerunOpts{runFlag: goTestRunFlag(tests), pkg: pkg})
+	streams, err := openStreamFiles(opts, true)
+	if err != nil {
+		return err
+	}
+	defer streams.close()
+	goTestProc, err := startGoTest(ctx, args, opts.Env)
+	if err != nil {
+		return errors.Wrapf(err, "failed to run %s", strings.Join(args, " "))
+	}
+	defer goTestProc.cancel()
+	streams.tee(&goTestProc)
+
+	cfg.Stdout = goTestProc.stdout
+	cfg.Stderr = goTestProc.stderr
+	if _, err := testjson.ScanTestOutput(cfg); err != nil {
+		return err
+	}
+	return goTestProc.cmd.Wait()
+}
+
+// goTestRunFlag returns a -run flag which matches exactly the named tests.
+func goTestRunFlag(tests []string) string {
+	quoted := make([]string, 0, len(tests))
+	for _, name := range tests {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "-run=^(" + strings.Join(quoted, "|") + ")$"
+}
+
+// failureRecorder is an EventHandler which records the failed tests of a run
+// so that they can be run again.
+type failureRecorder struct {
+	testjson.EventHandler
+	// attempt is the number of the run which is recorded, the first run of
+	// the tests is 1.
+	attempt int
+	tests   map[string][]string
+	seen    map[string]bool
+}
+
+func newFailureRecorder(handler testjson.EventHandler, attempt int) *failureRecorder {
+	return &failureRecorder{
+		EventHandler: handler,
+		attempt:      attempt,
+		tests:        make(map[string][]string),
+		seen:         make(map[string]bool),
+	}
+}
+
+func (r *failureRecorder) Event(event testjson.TestEvent, exec *testjson.Execution) error {
+	if event.Action == testjson.ActionFail {
+		r.add(event.Package, event.Test)
+	}
+	return r.EventHandler.Event(event, exec)
+}
+
+// add records a failed test. Subtests are not recorded because the root test
+// will also fail when a subtest fails, and running the root test will run
+// all of its subtests.
+func (r *failureRecorder) add(pkg, test string) {
+	key := pkg + "." + test
+	if !isRootTest(test) || r.seen[key] {
+		return
+	}
+	r.seen[key] = true
+	r.tests[pkg] = append(r.tests[pkg], test)
+}
+
+func (r *failureRecorder) count() int {
+	return len(r.seen)
+}
+
+func (r *failureRecorder) packages() []string {
+	pkgs := make([]string, 0, len(r.tests))
+	for pkg := range r.tests {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestRerunBlocker_PackageFilter(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/integration","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/integration","Test":"TestFlaky"}
+{"Action":"fail","Package":"example.com/integration"}
+{"Action":"run","Package":"example.com/unit","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/unit","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/unit"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Stderr:  strings.NewReader(""),
+		Handler: noopHandler{},
+	})
+	assert.NilError(t, err)
+	exitErr := &exitError{code: 1}
+
+	reason := rerunBlocker(exec, exitErr, regexp.MustCompile(""), 0)
+	assert.Equal(t, reason, "")
+
+	reason = rerunBlocker(exec, exitErr, regexp.MustCompile("integration"), 0)
+	assert.Equal(t, reason, "package example.com/unit does not match --rerun-fails-packages")
+}
+
+func TestRerunBlocker_MaxFailures(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne/sub"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne/sub"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestTwo"}
+{"Action":"fail","Package":"example.com/pkg"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Stderr:  strings.NewReader(""),
+		Handler: noopHandler{},
+	})
+	assert.NilError(t, err)
+	exitErr := &exitError{code: 1}
+
+	reason := rerunBlocker(exec, exitErr, regexp.MustCompile(""), 2)
+	assert.Equal(t, reason, "")
+
+	reason = rerunBlocker(exec, exitErr, regexp.MustCompile(""), 1)
+	assert.Equal(t, reason, "2 tests failed, more than --rerun-fails-max-failures=1")
+}
+
+type noopHandler struct{}
+
+func (noopHandler) Event(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+func (noopHandler) Err(string) error {
+	return nil
+}
+
+func TestValidateRerunOpts_RequiresRerunFails(t *testing.T) {
+	err := validateRerunOpts(&Options{RerunFailsPackages: "^example.com/flaky"})
+	assert.Error(t, err, "--rerun-fails-packages requires --rerun-fails")
+	err = validateRerunOpts(&Options{RerunFailsMaxFailures: 5})
+	assert.Error(t, err, "--rerun-fails-max-failures requires --rerun-fails")
+}
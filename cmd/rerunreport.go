@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// rerunResult is a test which failed, and later passed when it was rerun with
+// --rerun-fails.
+type rerunResult struct {
+	pkg      string
+	test     string
+	failures int
+	// attempts is the number of runs of the test, including the run which
+	// passed.
+	attempts int
+}
+
+// rerunAttempts is the number of the last run of each test which was rerun by
+// --rerun-fails, keyed by the package and the name of the root test. The
+// first run of the tests is 1. The number is counted by rerunFailed, because
+// the run of a TestCase is incremented once for each package which is rerun.
+type rerunAttempts map[string]int
+
+func (r rerunAttempts) add(pkg string, tests []string, attempt int) {
+	for _, test := range tests {
+		r[pkg+"."+test] = attempt
+	}
+}
+
+// get returns the number of runs of a test, or 0 if the test was not rerun.
+// Subtests are rerun with their root test.
+func (r rerunAttempts) get(pkg, test string) int {
+	return r[pkg+"."+strings.SplitN(test, "/", 2)[0]]
+}
+
+// rerunResults returns the tests which failed, and passed in a later run,
+// sorted by package and test name.
+func rerunResults(exec *testjson.Execution, reruns rerunAttempts) []rerunResult {
+	failures := make(map[testjson.TestCase]int)
+	for _, tc := range exec.Failed() {
+		failures[testjson.TestCase{Package: tc.Package, Test: tc.Test}]++
+	}
+	var results []rerunResult
+	for _, name := range exec.Packages() {
+		for _, tc := range exec.Package(name).Passed {
+			count := failures[testjson.TestCase{Package: tc.Package, Test: tc.Test}]
+			attempts := reruns.get(tc.Package, tc.Test)
+			if count == 0 || attempts == 0 {
+				continue
+			}
+			results = append(results, rerunResult{
+				pkg:      tc.Package,
+				test:     tc.Test,
+				failures: count,
+				attempts: attempts,
+			})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].pkg != results[j].pkg {
+			return results[i].pkg < results[j].pkg
+		}
+		return results[i].test < results[j].test
+	})
+	return results
+}
+
+// writeRerunReport writes a line to the --rerun-fails-report for each test
+// which failed, and passed when it was rerun. The file is written even when
+// no tests were rerun, so that an old report is not read by mistake.
+func writeRerunReport(filename string, exec *testjson.Execution, reruns rerunAttempts) error {
+	if filename == "" {
+		return nil
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to open rerun fails report")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.WithError(err).Error("failed to close rerun fails report")
+		}
+	}()
+	return errors.Wrap(printRerunReport(file, exec, reruns), "failed to write rerun fails report")
+}
+
+func printRerunReport(out io.Writer, exec *testjson.Execution, reruns rerunAttempts) error {
+	for _, result := range rerunResults(exec, reruns) {
+		_, err := fmt.Fprintf(out, "%s %s attempts=%d failures=%d\n",
+			result.pkg, result.test, result.attempts, result.failures)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRun_RerunFailsReport(t *testing.T) {
+	defer patchNoColor(true)()
+	dir, err := ioutil.TempDir("", "gotestsum-rerun-report")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	goBinary, err := filepath.Abs("testdata/rerun-fails-go.sh")
+	assert.NilError(t, err)
+	report := filepath.Join(dir, "rerun.txt")
+	opts := Options{
+		Format:                "short",
+		GoBinary:              goBinary,
+		Env:                   []string{"RERUN_STATE=" + dir},
+		RerunFailsMaxAttempts: 3,
+		RerunFailsReportFile:  report,
+	}
+	assert.NilError(t, Run(context.Background(), opts, new(bytes.Buffer)))
+
+	raw, err := ioutil.ReadFile(report)
+	assert.NilError(t, err)
+	expected := `example.com/a TestFlaky attempts=3 failures=2
+example.com/b TestConnect attempts=2 failures=1
+`
+	assert.Equal(t, string(raw), expected)
+}
+
+func TestValidateRerunOpts_Report(t *testing.T) {
+	opts := &Options{RerunFailsReportFile: "rerun.txt"}
+	assert.Error(t, validateRerunOpts(opts), "--rerun-fails-report requires --rerun-fails")
+	opts.RerunFailsMaxAttempts = 2
+	assert.NilError(t, validateRerunOpts(opts))
+}
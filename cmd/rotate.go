@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// rotatingFile is an io.WriteCloser which limits the size of a file. When a
+// write would make the file larger than maxSize, the file is renamed with a .1
+// suffix, replacing any previous .1 file, and a new file is created. At most
+// 2*maxSize bytes are kept on disk. Each write is kept in a single file, so
+// that a JSON event is never split across two files.
+type rotatingFile struct {
+	filename string
+	maxSize  int64
+	file     *os.File
+	size     int64
+}
+
+func newRotatingFile(filename string, maxSize int64, appendTo bool) (*rotatingFile, error) {
+	file, err := createFile(filename, appendTo)
+	if err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{filename: filename, maxSize: maxSize, file: file}
+	if appendTo {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close() // nolint: errcheck
+			return nil, err
+		}
+		r.size = info.Size()
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return errors.Wrap(err, "failed to rotate JSON file")
+	}
+	if err := os.Rename(r.filename, r.filename+".1"); err != nil {
+		return errors.Wrap(err, "failed to rotate JSON file")
+	}
+	file, err := os.Create(r.filename)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate JSON file")
+	}
+	r.file = file
+	r.size = 0
+	return nil
+}
+
+func (r *rotatingFile) Sync() error {
+	return r.file.Sync()
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
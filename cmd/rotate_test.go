@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-rotate")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "events.json")
+	file, err := newRotatingFile(filename, 10, false)
+	assert.NilError(t, err)
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n", "a long line\n"} {
+		_, err := file.Write([]byte(line))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, file.Close())
+
+	assertFile(t, filename, "a long line\n")
+	assertFile(t, filename+".1", "four\n")
+}
+
+func TestRotatingFileAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-rotate")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	filename := filepath.Join(dir, "events.json")
+	assert.NilError(t, ioutil.WriteFile(filename, []byte("previous\n"), 0644))
+	file, err := newRotatingFile(filename, 12, true)
+	assert.NilError(t, err)
+	_, err = file.Write([]byte("next\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, file.Close())
+
+	assertFile(t, filename, "next\n")
+	assertFile(t, filename+".1", "previous\n")
+}
+
+func assertFile(t *testing.T, filename string, expected string) {
+	raw, err := ioutil.ReadFile(filename)
+	assert.NilError(t, err)
+	assert.Equal(t, string(raw), expected)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// testFilters returns the -run and -skip flags from the go test arguments, in
+// the form which would be typed in a shell.
+func testFilters(args []string) []string {
+	var filters []string
+	for i := 0; i < len(args); i++ {
+		flag := strings.TrimPrefix(trimFlagDashes(args[i]), "test.")
+		name, value := flag, ""
+		hasValue := false
+		if eq := strings.Index(flag, "="); eq >= 0 {
+			name, value, hasValue = flag[:eq], flag[eq+1:], true
+		}
+		if name != "run" && name != "skip" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		filters = append(filters, "-"+name+" "+shellQuote(value))
+	}
+	return filters
+}
+
+// printTestFilters prints a note after the summary when the tests were
+// filtered by -run or -skip, so that the counts in the summary are not read as
+// the counts of all the tests in the packages.
+func printTestFilters(out io.Writer, opts *Options) {
+	filters := testFilters(goTestCmdArgs(opts, rerunOpts{}))
+	if len(filters) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Tests were filtered with %s, other tests in the packages were not run\n",
+		strings.Join(filters, " "))
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+func TestTestFilters(t *testing.T) {
+	args := []string{
+		"go", "test", "-json", "-run", "TestA|TestB", "-count=1", "--skip=TestC",
+		"./pkg", "-args", "-test.run=TestD",
+	}
+	expected := []string{"-run 'TestA|TestB'", "-skip TestC", "-run TestD"}
+	assert.DeepEqual(t, testFilters(args), expected)
+	assert.Assert(t, testFilters([]string{"go", "test", "-json", "-runs=3"}) == nil)
+}
+
+func TestPrintTestFilters(t *testing.T) {
+	out := new(bytes.Buffer)
+	printTestFilters(out, &Options{Args: []string{"./pkg"}})
+	assert.Equal(t, out.String(), "")
+
+	printTestFilters(out, &Options{Args: []string{"-run", "TestA", "./pkg"}})
+	expected := "Tests were filtered with -run TestA, other tests in the packages were not run\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestSummarizer_NoSummaryAllHidesTestFilters(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(`{"Action":"pass","Package":"example.com/pkg","Test":"TestA"}`),
+		Stderr:  strings.NewReader(""),
+		Handler: noopHandler{},
+	})
+	assert.NilError(t, err)
+	opts := &Options{Args: []string{"-run", "TestA", "./pkg"}, NoSummary: []string{"all"}}
+	out := new(bytes.Buffer)
+	assert.NilError(t, summarizer(opts)(out, exec))
+	assert.Assert(t, !strings.Contains(out.String(), "Tests were filtered"), out.String())
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// interruptHandler cancels a test run when the process receives SIGINT or
+// SIGTERM, so that the summary of the partial run can still be printed. A
+// second signal exits the process immediately.
+type interruptHandler struct {
+	signals chan os.Signal
+	done    chan struct{}
+	mu      sync.Mutex
+	signal  os.Signal
+}
+
+func handleInterrupts(cancel func()) *interruptHandler {
+	h := &interruptHandler{
+		signals: make(chan os.Signal, 2),
+		done:    make(chan struct{}),
+	}
+	signal.Notify(h.signals, os.Interrupt, syscall.SIGTERM)
+	go h.wait(cancel)
+	return h
+}
+
+func (h *interruptHandler) wait(cancel func()) {
+	select {
+	case <-h.done:
+		return
+	case sig := <-h.signals:
+		log.Debugf("received %s, stopping the test run", sig)
+		h.mu.Lock()
+		h.signal = sig
+		h.mu.Unlock()
+		cancel()
+	}
+	select {
+	case <-h.done:
+	case sig := <-h.signals:
+		log.Debugf("received %s, exiting", sig)
+		os.Exit(signalExitCode(sig))
+	}
+}
+
+// interrupted returns the signal that interrupted the run, or nil if the run
+// was not interrupted.
+func (h *interruptHandler) interrupted() os.Signal {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.signal
+}
+
+func (h *interruptHandler) stop() {
+	signal.Stop(h.signals)
+	close(h.done)
+}
+
+// signalExitCode returns the exit code used by shells for a process which was
+// terminated by sig.
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
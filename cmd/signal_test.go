@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSignalExitCode(t *testing.T) {
+	var testcases = []struct {
+		sig      os.Signal
+		expected int
+	}{
+		{sig: os.Interrupt, expected: 130},
+		{sig: syscall.SIGTERM, expected: 143},
+		{sig: fakeSignal{}, expected: 1},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.sig.String(), func(t *testing.T) {
+			assert.Equal(t, signalExitCode(tc.sig), tc.expected)
+		})
+	}
+}
+
+type fakeSignal struct{}
+
+func (fakeSignal) String() string { return "fake" }
+
+func (fakeSignal) Signal() {}
+
+func TestInterruptHandler_Interrupted(t *testing.T) {
+	cancelled := make(chan struct{})
+	h := handleInterrupts(func() { close(cancelled) })
+	defer h.stop()
+	assert.Assert(t, h.interrupted() == nil)
+
+	h.signals <- syscall.SIGTERM
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the run to be cancelled")
+	}
+	assert.Equal(t, h.interrupted(), syscall.SIGTERM)
+}
+
+func TestInterruptHandler_StopWithoutSignal(t *testing.T) {
+	h := handleInterrupts(func() { t.Error("unexpected cancel") })
+	h.stop()
+	assert.Assert(t, h.interrupted() == nil)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamFiles are the --stdout-file and --stderr-file, which receive a copy of
+// the stdout and stderr of the go test command as they are read, before the
+// output is parsed, so that the exact output of go test can be read when the
+// parsing is suspected of losing output.
+type streamFiles struct {
+	stdout *os.File
+	stderr *os.File
+}
+
+// openStreamFiles opens the --stdout-file and --stderr-file before go test is
+// started, so that go test is not run when a file can not be opened. The files
+// are truncated unless appendTo is true, which is used to add the output of the
+// reruns of failed tests.
+func openStreamFiles(opts *Options, appendTo bool) (*streamFiles, error) {
+	files := &streamFiles{}
+	streams := []struct {
+		filename string
+		file     **os.File
+	}{
+		{filename: opts.StdoutFile, file: &files.stdout},
+		{filename: opts.StderrFile, file: &files.stderr},
+	}
+	for _, stream := range streams {
+		if stream.filename == "" {
+			continue
+		}
+		file, err := createFile(stream.filename, appendTo)
+		if err != nil {
+			files.close()
+			return nil, errors.Wrap(err, "failed to open go test output file")
+		}
+		*stream.file = file
+	}
+	return files, nil
+}
+
+// tee copies the stdout and stderr of p to the files as they are read.
+func (f *streamFiles) tee(p *proc) {
+	if f.stdout != nil {
+		p.stdout = io.TeeReader(p.stdout, f.stdout)
+	}
+	if f.stderr != nil {
+		p.stderr = io.TeeReader(p.stderr, f.stderr)
+	}
+}
+
+func (f *streamFiles) close() {
+	for _, file := range []*os.File{f.stdout, f.stderr} {
+		if file == nil {
+			continue
+		}
+		if err := file.Close(); err != nil {
+			log.WithError(err).Errorf("failed to close %s", file.Name())
+		}
+	}
+}
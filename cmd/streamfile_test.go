@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRun_StdoutFileAndStderrFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-streams")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	script := `echo '{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}'
+echo 'not json'
+echo 'warning: from go' >&2`
+	opts := Options{
+		Format:          "short",
+		RawCommandShell: true,
+		Args:            []string{script},
+		StdoutFile:      filepath.Join(dir, "stdout.txt"),
+		StderrFile:      filepath.Join(dir, "stderr.txt"),
+	}
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.NilError(t, err)
+
+	stdout, err := ioutil.ReadFile(opts.StdoutFile)
+	assert.NilError(t, err)
+	expected := `{"Action":"pass","Package":"example.com/pkg","Test":"TestOne"}` + "\nnot json\n"
+	assert.Equal(t, string(stdout), expected)
+
+	stderr, err := ioutil.ReadFile(opts.StderrFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(stderr), "warning: from go\n")
+}
+
+func TestRun_StdoutFileNotWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gotestsum-streams")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// go test must not be started when the file can not be opened
+	started := filepath.Join(dir, "started")
+	opts := Options{
+		Format:          "short",
+		RawCommandShell: true,
+		Args:            []string{"touch " + started},
+		StdoutFile:      "/missing/dir/stdout.txt",
+	}
+	err = Run(context.Background(), opts, new(bytes.Buffer))
+	assert.ErrorContains(t, err, "failed to open go test output file")
+	_, err = os.Stat(started)
+	assert.Assert(t, os.IsNotExist(err), "go test was started")
+}
@@ -0,0 +1,11 @@
+// +build !darwin,!linux
+
+package cmd
+
+import "os"
+
+// terminalWidth returns the number of columns of the terminal, or 0 if the
+// width is not known.
+func terminalWidth(_ *os.File) int {
+	return 0
+}
@@ -0,0 +1,34 @@
+#!/bin/sh
+# A fake go binary for --rerun-fails. TestFlaky in example.com/a fails twice,
+# and TestConnect in example.com/b fails once. The runs are counted with files
+# in $RERUN_STATE.
+case "$*" in
+*example.com/a)
+    echo >> "$RERUN_STATE/a"
+    if [ "$(wc -l < "$RERUN_STATE/a")" -lt 2 ]; then
+        echo '{"Action":"run","Package":"example.com/a","Test":"TestFlaky"}'
+        echo '{"Action":"fail","Package":"example.com/a","Test":"TestFlaky"}'
+        echo '{"Action":"fail","Package":"example.com/a"}'
+        exit 1
+    fi
+    echo '{"Action":"run","Package":"example.com/a","Test":"TestFlaky"}'
+    echo '{"Action":"pass","Package":"example.com/a","Test":"TestFlaky"}'
+    echo '{"Action":"pass","Package":"example.com/a"}'
+    ;;
+*example.com/b)
+    echo '{"Action":"run","Package":"example.com/b","Test":"TestConnect"}'
+    echo '{"Action":"pass","Package":"example.com/b","Test":"TestConnect"}'
+    echo '{"Action":"pass","Package":"example.com/b"}'
+    ;;
+*)
+    echo '{"Action":"run","Package":"example.com/a","Test":"TestFlaky"}'
+    echo '{"Action":"fail","Package":"example.com/a","Test":"TestFlaky"}'
+    echo '{"Action":"fail","Package":"example.com/a"}'
+    echo '{"Action":"run","Package":"example.com/b","Test":"TestConnect"}'
+    echo '{"Action":"fail","Package":"example.com/b","Test":"TestConnect"}'
+    echo '{"Action":"run","Package":"example.com/b","Test":"TestStable"}'
+    echo '{"Action":"pass","Package":"example.com/b","Test":"TestStable"}'
+    echo '{"Action":"fail","Package":"example.com/b"}'
+    exit 1
+    ;;
+esac
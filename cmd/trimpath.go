@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// trimPathWriter is an io.Writer which removes the --trim-path-prefix values
+// from the output, so that the file paths in the output are relative to the
+// prefix. Each write is expected to contain whole lines, which is true for the
+// formatted events and the summary.
+type trimPathWriter struct {
+	out      io.Writer
+	replacer *strings.Replacer
+}
+
+func newTrimPathWriter(out io.Writer, prefixes []string) *trimPathWriter {
+	var pairs []string
+	for _, prefix := range prefixes {
+		pairs = append(pairs, strings.TrimSuffix(prefix, "/")+"/", "")
+	}
+	return &trimPathWriter{out: out, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (w *trimPathWriter) Write(p []byte) (int, error) {
+	if _, err := w.replacer.WriteString(w.out, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// validateTrimPathPrefix returns an error if a value of --trim-path-prefix is
+// empty, because every "/" in the output would be removed.
+func validateTrimPathPrefix(prefixes []string) error {
+	for _, prefix := range prefixes {
+		if strings.TrimSuffix(prefix, "/") == "" {
+			return errors.Errorf("invalid --trim-path-prefix %q, must not be empty", prefix)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTrimPathWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	w := newTrimPathWriter(out, []string{"/home/runner/work/repo", "/usr/local/go/"})
+	_, err := fmt.Fprint(w, "\t/home/runner/work/repo/pkg/foo_test.go:12: failed\n")
+	assert.NilError(t, err)
+	_, err = fmt.Fprint(w, "\t/usr/local/go/src/testing/testing.go:865 +0xc0\n")
+	assert.NilError(t, err)
+	_, err = fmt.Fprint(w, "/home/runner/work/repository/foo.go:1\n")
+	assert.NilError(t, err)
+
+	expected := "\tpkg/foo_test.go:12: failed\n" +
+		"\tsrc/testing/testing.go:865 +0xc0\n" +
+		"/home/runner/work/repository/foo.go:1\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestValidateTrimPathPrefix(t *testing.T) {
+	assert.NilError(t, validateTrimPathPrefix([]string{"/home/runner"}))
+	assert.ErrorContains(t, validateTrimPathPrefix([]string{"/"}), "must not be empty")
+	assert.ErrorContains(t, validateTrimPathPrefix([]string{""}), "must not be empty")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+)
+
+// validateUntilFail returns an error if --until-fail is used with an option
+// which does not run go test, or with an option which hides the failure the
+// run is looking for. --rerun-fails would rerun the failed tests until they
+// pass, --quiet-on-success would buffer the output of every iteration, and
+// --append would add the files of the runs which passed to the files of the
+// run which failed.
+func validateUntilFail(opts *Options) error {
+	switch {
+	case opts.UntilFail == 0:
+		return nil
+	case opts.Watch:
+		return errors.New("--until-fail can not be used with --watch")
+	case opts.RawFromFile != "" || opts.JUnitFromFile != "" || opts.MergeJSON:
+		return errors.New("--until-fail requires a go test command, not an input file")
+	case opts.RerunFailsMaxAttempts > 0:
+		return errors.New("--until-fail can not be used with --rerun-fails")
+	case opts.QuietOnSuccess:
+		return errors.New("--until-fail can not be used with --quiet-on-success")
+	case opts.Append:
+		return errors.New("--until-fail can not be used with --append")
+	}
+	return nil
+}
+
+// runUntilFail runs go test until a run fails, or until opts.UntilFail runs
+// passed. A negative opts.UntilFail runs go test until a run fails. Each run
+// replaces the --jsonfile and --junitfile of the previous run, so the files of
+// the run which failed are kept. A run which was interrupted, stopped early,
+// or could not run the tests, is reported as stopped instead of failed.
+func runUntilFail(ctx context.Context, opts *Options, out io.Writer) error {
+	for iteration := 1; opts.UntilFail < 0 || iteration <= opts.UntilFail; iteration++ {
+		fmt.Fprintln(out, color.CyanString("=== Iteration %d", iteration))
+		if err := runGoTest(ctx, opts, out, rerunOpts{}); err != nil {
+			result := "Stopped"
+			if isTestFailure(err) {
+				result = "Failed"
+			}
+			fmt.Fprintln(out, color.RedString("\n%s on iteration %d (--until-fail)", result, iteration))
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	fmt.Fprintln(out, color.GreenString("\nAll %d iterations passed (--until-fail)", opts.UntilFail))
+	return nil
+}
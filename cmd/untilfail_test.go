@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestRun_UntilFail(t *testing.T) {
+	defer patchNoColor(true)()
+	dir, err := ioutil.TempDir("", "gotestsum-until-fail")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// the script fails on the third run
+	count := filepath.Join(dir, "count")
+	script := `echo x >> ` + count + `
+if [ "$(wc -l < ` + count + `)" -ge 3 ]; then
+  echo '{"Action":"fail","Package":"pkg","Test":"TestFlaky"}'
+  exit 1
+fi
+echo '{"Action":"pass","Package":"pkg","Test":"TestFlaky"}'`
+	opts := Options{
+		Format:          "short",
+		RawCommandShell: true,
+		Args:            []string{script},
+		UntilFail:       -1,
+	}
+	out := new(bytes.Buffer)
+	err = Run(context.Background(), opts, out)
+	assert.Equal(t, ExitCodeWithDefault(err), 1)
+	assert.Assert(t, cmp.Contains(out.String(), "=== Iteration 3\n"))
+	assert.Assert(t, cmp.Contains(out.String(), "Failed on iteration 3 (--until-fail)"))
+}
+
+func TestRun_UntilFailAllPassed(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:     "short",
+		RawCommand: true,
+		Args:       []string{"cat", "testdata/skipped.json"},
+		UntilFail:  2,
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, Run(context.Background(), opts, out))
+	assert.Assert(t, cmp.Contains(out.String(), "=== Iteration 2\n"))
+	assert.Assert(t, cmp.Contains(out.String(), "All 2 iterations passed (--until-fail)"))
+}
+
+func TestValidateUntilFail(t *testing.T) {
+	assert.NilError(t, validateUntilFail(&Options{UntilFail: 2}))
+	err := validateUntilFail(&Options{UntilFail: 2, RerunFailsMaxAttempts: 3})
+	assert.Error(t, err, "--until-fail can not be used with --rerun-fails")
+	err = validateUntilFail(&Options{UntilFail: -1, QuietOnSuccess: true})
+	assert.Error(t, err, "--until-fail can not be used with --quiet-on-success")
+	err = validateUntilFail(&Options{UntilFail: 2, Append: true})
+	assert.Error(t, err, "--until-fail can not be used with --append")
+}
+
+func TestRun_UntilFailStopped(t *testing.T) {
+	defer patchNoColor(true)()
+	opts := Options{
+		Format:     "short",
+		RawCommand: true,
+		Args:       []string{"/missing/command"},
+		UntilFail:  2,
+	}
+	out := new(bytes.Buffer)
+	err := Run(context.Background(), opts, out)
+	assert.Assert(t, !IsExitError(err))
+	assert.Assert(t, cmp.Contains(out.String(), "Stopped on iteration 1 (--until-fail)"))
+	assert.Assert(t, !strings.Contains(out.String(), "Failed on iteration"))
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// The version, commit, and build date of the binary. They are set when the
+// binary is built with:
+//
+//	-ldflags "-X gotest.tools/gotestsum/cmd.version=... -X ..."
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+func printVersion(out io.Writer, name string) {
+	fmt.Fprintf(out, "%s version %s (commit %s, built %s)\n", name, version, commit, date)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const watchPollInterval = 500 * time.Millisecond
+
+// runWatcher runs the tests, and then runs them again each time a go file
+// is modified. When all the modified files are in the same directory only
+// the tests for that package are run, otherwise the full go test command is
+// run. The screen is cleared before each run when screen is not nil. The
+// watcher stops when ctx is cancelled.
+func runWatcher(ctx context.Context, opts *Options, out io.Writer, screen io.Writer) error {
+	switch {
+	case isRawCommand(opts):
+		return errors.New("--watch can not be used with --raw-command")
+	case opts.RawFromFile != "":
+		return errors.New("--watch can not be used with --raw-from-file")
+	case opts.JUnitFromFile != "":
+		return errors.New("--watch can not be used with --junit-from-file")
+	case opts.MergeJSON:
+		return errors.New("--watch can not be used with --merge-json")
+	}
+	watcher := newFileWatcher(".")
+	if err := watcher.scan(); err != nil {
+		return err
+	}
+	runWatchIteration(ctx, opts, out, rerunOpts{})
+	for {
+		changed, err := watcher.wait(ctx, watchPollInterval)
+		if err != nil {
+			return err
+		}
+		clearScreen(screen)
+		runWatchIteration(ctx, opts, out, watchTarget(opts, changed))
+	}
+}
+
+func runWatchIteration(ctx context.Context, opts *Options, out io.Writer, target rerunOpts) {
+	if target.pkg != "" {
+		fmt.Fprintf(out, "Running tests for %s\n", target.pkg)
+	}
+	if err := runGoTest(ctx, opts, out, target); err != nil && !IsExitError(err) {
+		log.WithError(err).Error("failed to run tests")
+	}
+}
+
+// watchTarget returns the package to test when all the changed files are in
+// a single directory. When the go test command has arguments, or the package
+// is not obvious, the full go test command is run.
+func watchTarget(opts *Options, changed []string) rerunOpts {
+	if len(opts.Args) > 0 || len(testPackages(opts, "")) > 0 {
+		return rerunOpts{}
+	}
+	dirs := make(map[string]bool)
+	for _, filename := range changed {
+		dirs[filepath.Dir(filename)] = true
+	}
+	if len(dirs) != 1 {
+		return rerunOpts{}
+	}
+	dir := filepath.ToSlash(filepath.Dir(changed[0]))
+	if dir == "." {
+		return rerunOpts{pkg: "."}
+	}
+	return rerunOpts{pkg: "./" + dir}
+}
+
+// terminalOutput returns out when it is a terminal, otherwise nil. It must be
+// called before out is wrapped by other writers, like the --output-file.
+func terminalOutput(out io.Writer) io.Writer {
+	if f, ok := out.(*os.File); ok && isTerminal(f) {
+		return f
+	}
+	return nil
+}
+
+func clearScreen(screen io.Writer) {
+	if screen != nil {
+		fmt.Fprint(screen, "\033[H\033[2J")
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// fileWatcher polls the modification time of all the go files in a
+// directory tree.
+type fileWatcher struct {
+	root    string
+	modTime map[string]time.Time
+}
+
+func newFileWatcher(root string) *fileWatcher {
+	return &fileWatcher{root: root, modTime: make(map[string]time.Time)}
+}
+
+// wait blocks until one or more go files are created, modified, or removed,
+// and returns the names of the changed files. Changes are debounced by
+// waiting until a full poll interval passes with no more changes. The error
+// of ctx is returned when ctx is cancelled.
+func (w *fileWatcher) wait(ctx context.Context, interval time.Duration) ([]string, error) {
+	changed := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		previous := w.modTime
+		if err := w.scan(); err != nil {
+			return nil, err
+		}
+		files := diffModTimes(previous, w.modTime)
+		if len(files) == 0 && len(changed) > 0 {
+			return sortedSet(changed), nil
+		}
+		for _, filename := range files {
+			changed[filename] = true
+		}
+	}
+}
+
+func (w *fileWatcher) scan() error {
+	modTime := make(map[string]time.Time)
+	err := filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.IsDir() && path != w.root && isIgnoredDir(info.Name()):
+			return filepath.SkipDir
+		case !info.IsDir() && strings.HasSuffix(path, ".go"):
+			modTime[path] = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to watch files")
+	}
+	w.modTime = modTime
+	return nil
+}
+
+// isIgnoredDir returns true for directories which are ignored by the go tool.
+func isIgnoredDir(name string) bool {
+	return name == "vendor" ||
+		name == "testdata" ||
+		strings.HasPrefix(name, ".") ||
+		strings.HasPrefix(name, "_")
+}
+
+func diffModTimes(previous, current map[string]time.Time) []string {
+	var changed []string
+	for filename, modTime := range current {
+		if prev, ok := previous[filename]; !ok || !prev.Equal(modTime) {
+			changed = append(changed, filename)
+		}
+	}
+	for filename := range previous {
+		if _, ok := current[filename]; !ok {
+			changed = append(changed, filename)
+		}
+	}
+	return changed
+}
+
+func sortedSet(set map[string]bool) []string {
+	items := make([]string, 0, len(set))
+	for item := range set {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestWatchTarget(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		opts     Options
+		changed  []string
+		expected rerunOpts
+	}{
+		{
+			name:     "single directory",
+			changed:  []string{"pkg/a.go", "pkg/a_test.go"},
+			expected: rerunOpts{pkg: "./pkg"},
+		},
+		{
+			name:     "nested directory",
+			changed:  []string{"internal/pkg/a.go"},
+			expected: rerunOpts{pkg: "./internal/pkg"},
+		},
+		{
+			name:     "root directory",
+			changed:  []string{"main.go"},
+			expected: rerunOpts{pkg: "."},
+		},
+		{
+			name:    "multiple directories",
+			changed: []string{"pkg/a.go", "other/b.go"},
+		},
+		{
+			name:    "go test args",
+			opts:    Options{Args: []string{"-run", "TestA"}},
+			changed: []string{"pkg/a.go"},
+		},
+		{
+			name:    "packages",
+			opts:    Options{Packages: []string{"./other"}},
+			changed: []string{"pkg/a.go"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := watchTarget(&tc.opts, tc.changed)
+			assert.Equal(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestDiffModTimes(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Second)
+
+	var testcases = []struct {
+		name     string
+		previous map[string]time.Time
+		current  map[string]time.Time
+		expected []string
+	}{
+		{
+			name:     "no changes",
+			previous: map[string]time.Time{"a.go": now},
+			current:  map[string]time.Time{"a.go": now},
+		},
+		{
+			name:     "modified",
+			previous: map[string]time.Time{"a.go": now, "b.go": now},
+			current:  map[string]time.Time{"a.go": later, "b.go": now},
+			expected: []string{"a.go"},
+		},
+		{
+			name:     "created and removed",
+			previous: map[string]time.Time{"a.go": now},
+			current:  map[string]time.Time{"b.go": now},
+			expected: []string{"a.go", "b.go"},
+		},
+		{
+			name:     "first scan",
+			previous: map[string]time.Time{},
+			current:  map[string]time.Time{"a.go": now},
+			expected: []string{"a.go"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := diffModTimes(tc.previous, tc.current)
+			sort.Strings(actual)
+			assert.DeepEqual(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestIsIgnoredDir(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		expected bool
+	}{
+		{name: "vendor", expected: true},
+		{name: "testdata", expected: true},
+		{name: ".git", expected: true},
+		{name: "_build", expected: true},
+		{name: "pkg", expected: false},
+		{name: "internal", expected: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, isIgnoredDir(tc.name), tc.expected)
+		})
+	}
+}
+
+func TestRunWatcher_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &Options{Format: "short", Watch: true, GoBinary: "true"}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- runWatcher(ctx, opts, new(bytes.Buffer), nil)
+	}()
+	time.AfterFunc(100*time.Millisecond, cancel)
+	select {
+	case err := <-errs:
+		assert.Equal(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watcher did not stop when the context was cancelled")
+	}
+}
+
+func TestTerminalOutput(t *testing.T) {
+	assert.Assert(t, terminalOutput(new(bytes.Buffer)) == nil)
+}
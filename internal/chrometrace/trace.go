@@ -0,0 +1,159 @@
+// Package chrometrace creates a Chrome trace event file from a
+// testjson.Execution, which shows the tests on a timeline in
+// chrome://tracing or Perfetto.
+package chrometrace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Trace is a document in the JSON object format of the Chrome trace event
+// format.
+type Trace struct {
+	TraceEvents     []Event `json:"traceEvents"`
+	DisplayTimeUnit string  `json:"displayTimeUnit"`
+}
+
+// Event is a single trace event. Tests are complete events (ph X), and the
+// names of the rows are metadata events (ph M).
+type Event struct {
+	Name  string `json:"name"`
+	Cat   string `json:"cat,omitempty"`
+	Phase string `json:"ph"`
+	// Timestamp is the start of the event, in microseconds from the start of
+	// the first test.
+	Timestamp int64 `json:"ts"`
+	// Duration of the event in microseconds.
+	Duration int64             `json:"dur,omitempty"`
+	PID      int               `json:"pid"`
+	TID      int               `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// Write creates a trace document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution) error {
+	return errors.Wrap(write(out, generate(exec)), "failed to write trace file")
+}
+
+// span is a test case with its result and start time.
+type span struct {
+	tc     testjson.TestCase
+	result string
+	start  time.Time
+}
+
+// generate creates a trace with a process for each package, and a complete
+// event for each test case. Test cases which overlap in time, like parallel
+// tests, are put on different threads of the process. Test cases without a
+// time are omitted.
+func generate(exec *testjson.Execution) Trace {
+	var spans [][]span
+	var origin time.Time
+	for _, name := range exec.Packages() {
+		pkgSpans := packageSpans(exec.Package(name))
+		for _, s := range pkgSpans {
+			if origin.IsZero() || s.start.Before(origin) {
+				origin = s.start
+			}
+		}
+		spans = append(spans, pkgSpans)
+	}
+
+	trace := Trace{TraceEvents: []Event{}, DisplayTimeUnit: "ms"}
+	for i, pkgSpans := range spans {
+		if len(pkgSpans) == 0 {
+			continue
+		}
+		pid := i + 1
+		trace.TraceEvents = append(trace.TraceEvents, Event{
+			Name:  "process_name",
+			Phase: "M",
+			PID:   pid,
+			Args:  map[string]string{"name": pkgSpans[0].tc.Package},
+		})
+		trace.TraceEvents = append(trace.TraceEvents, spanEvents(pkgSpans, pid, origin)...)
+	}
+	return trace
+}
+
+func packageSpans(pkg *testjson.Package) []span {
+	var spans []span
+	add := func(testCases []testjson.TestCase, result string) {
+		for _, tc := range testCases {
+			if tc.Ended().IsZero() {
+				continue
+			}
+			spans = append(spans, span{tc: tc, result: result, start: tc.Ended().Add(-tc.Elapsed)})
+		}
+	}
+	add(pkg.Passed, "pass")
+	add(pkg.Failed, "fail")
+	add(pkg.Skipped, "skip")
+	sort.SliceStable(spans, func(i, j int) bool {
+		if !spans[i].start.Equal(spans[j].start) {
+			return spans[i].start.Before(spans[j].start)
+		}
+		// a parent test starts before its subtests, and ends after them
+		return spans[i].tc.Elapsed > spans[j].tc.Elapsed
+	})
+	return spans
+}
+
+// spanEvents returns the events for the spans of a package, which must be
+// sorted by start time. Each span is put on the first thread which is free
+// at the start of the span, or which has a span that contains it.
+func spanEvents(spans []span, pid int, origin time.Time) []Event {
+	var threads [][]time.Time // the end times of the open spans of each thread
+	events := make([]Event, 0, len(spans))
+	for _, s := range spans {
+		end := s.tc.Ended()
+		tid := -1
+		for i, open := range threads {
+			open = closeSpans(open, s.start)
+			threads[i] = open
+			if len(open) == 0 || !end.After(open[len(open)-1]) {
+				tid = i
+				break
+			}
+		}
+		if tid < 0 {
+			tid = len(threads)
+			threads = append(threads, nil)
+		}
+		threads[tid] = append(threads[tid], end)
+		events = append(events, Event{
+			Name:      s.tc.Test,
+			Cat:       s.result,
+			Phase:     "X",
+			Timestamp: s.start.Sub(origin).Nanoseconds() / 1000,
+			Duration:  s.tc.Elapsed.Nanoseconds() / 1000,
+			PID:       pid,
+			TID:       tid + 1,
+			Args:      map[string]string{"package": s.tc.Package, "result": s.result},
+		})
+	}
+	return events
+}
+
+// closeSpans removes the end times of the spans which ended at or before t.
+func closeSpans(open []time.Time, t time.Time) []time.Time {
+	for len(open) > 0 && !open[len(open)-1].After(t) {
+		open = open[:len(open)-1]
+	}
+	return open
+}
+
+func write(out io.Writer, trace Trace) error {
+	doc, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(doc, '\n'))
+	return err
+}
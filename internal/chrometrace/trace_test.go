@@ -0,0 +1,39 @@
+package chrometrace
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/golden"
+	"gotest.tools/gotestsum/internal/testexec"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := Write(out, testexec.Scan(t, testexec.ReadTestData(t, "out")))
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "chrometrace-report.golden")
+}
+
+func TestGenerate_OverlappingTests(t *testing.T) {
+	event := func(ended, action, test string, elapsed int) string {
+		return fmt.Sprintf(`{"Time":"2020-01-02T03:04:%sZ","Action":"%s",`+
+			`"Package":"pkg","Test":"%s","Elapsed":%d}`+"\n", ended, action, test, elapsed)
+	}
+	events := event("07", "pass", "TestParent/sub", 1) +
+		event("09", "pass", "TestParent", 4) +
+		event("10", "pass", "TestParallel", 3) +
+		event("11", "fail", "TestLast", 1)
+	trace := generate(testexec.Scan(t, strings.NewReader(events)))
+	threads := make(map[string]int)
+	for _, event := range trace.TraceEvents {
+		if event.Phase == "X" {
+			threads[event.Name] = event.TID
+		}
+	}
+	expected := map[string]int{"TestParent": 1, "TestParent/sub": 1, "TestParallel": 2, "TestLast": 1}
+	assert.DeepEqual(t, threads, expected)
+}
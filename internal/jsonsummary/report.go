@@ -0,0 +1,82 @@
+/*Package jsonsummary creates a JSON summary report from a testjson.Execution.
+ */
+package jsonsummary
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Summary is an aggregated report of a test run. Passed, Failed, and Skipped
+// are counts of tests, and add up to Total. A package which failed without a
+// test failure, for example in TestMain, is counted in PackagesFailed.
+type Summary struct {
+	Total          int `json:"total"`
+	Passed         int `json:"passed"`
+	Failed         int `json:"failed"`
+	Skipped        int `json:"skipped"`
+	PackagesFailed int `json:"packages_failed"`
+	// Elapsed time of the run in seconds
+	Elapsed  float64   `json:"elapsed"`
+	Failures []Failure `json:"failures"`
+	Errors   []string  `json:"errors"`
+}
+
+// Failure is a single failed test, or a package which failed without a test
+// failure.
+type Failure struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+	// Elapsed time of the test in seconds
+	Elapsed float64 `json:"elapsed"`
+	Output  string  `json:"output"`
+}
+
+// Write creates a JSON summary document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution) error {
+	return errors.Wrap(write(out, generate(exec)), "failed to write JSON summary")
+}
+
+func generate(exec *testjson.Execution) Summary {
+	summary := Summary{
+		Total:    exec.Total(),
+		Skipped:  len(exec.Skipped()),
+		Elapsed:  exec.Elapsed().Seconds(),
+		Failures: []Failure{},
+		Errors:   exec.Errors(),
+	}
+	for _, tc := range exec.Failed() {
+		pkg := exec.Package(tc.Package)
+		summary.Failures = append(summary.Failures, Failure{
+			Package: tc.Package,
+			Test:    tc.Test,
+			Elapsed: tc.Elapsed.Seconds(),
+			Output:  strings.Join(pkg.OutputLines(tc), ""),
+		})
+		if tc.Test == "" {
+			summary.PackagesFailed++
+			continue
+		}
+		summary.Failed++
+	}
+	for _, name := range exec.Packages() {
+		summary.Passed += len(exec.Package(name).Passed)
+	}
+	if summary.Errors == nil {
+		summary.Errors = []string{}
+	}
+	return summary
+}
+
+func write(out io.Writer, summary Summary) error {
+	doc, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(doc, '\n'))
+	return err
+}
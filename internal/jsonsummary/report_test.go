@@ -0,0 +1,22 @@
+package jsonsummary
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/golden"
+	"gotest.tools/gotestsum/internal/testexec"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	summary := generate(testexec.ScanTestData(t))
+	// Elapsed is the wall clock time of the test run, which is not stable
+	summary.Elapsed = 0
+
+	err := write(out, summary)
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "jsonsummary-report.golden")
+	assert.Equal(t, summary.Passed+summary.Failed+summary.Skipped, summary.Total)
+}
@@ -0,0 +1,56 @@
+package junitxml
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Read parses a JUnit XML document. The root element of the document may be
+// either a testsuites element, or a single testsuite element.
+func Read(in io.Reader) (JUnitTestSuites, error) {
+	var suites JUnitTestSuites
+	decoder := xml.NewDecoder(in)
+	for {
+		token, err := decoder.Token()
+		switch {
+		case err == io.EOF:
+			return suites, errors.New("failed to parse JUnit XML: no testsuites element")
+		case err != nil:
+			return suites, errors.Wrap(err, "failed to parse JUnit XML")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "testsuites":
+			err = decoder.DecodeElement(&suites, &start)
+		case "testsuite":
+			suites.Suites = make([]JUnitTestSuite, 1)
+			err = decoder.DecodeElement(&suites.Suites[0], &start)
+		default:
+			return suites, errors.Errorf("unsupported JUnit XML: the root element is <%s>, "+
+				"expected <testsuites> or <testsuite>", start.Name.Local)
+		}
+		return suites, errors.Wrap(err, "failed to parse JUnit XML")
+	}
+}
+
+// ParseTime parses the value of a time attribute. The value is a number of
+// seconds, with an optional s suffix. An empty value is 0.
+func ParseTime(value string) (time.Duration, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "s")
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid JUnit XML time %q", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,71 @@
+package junitxml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRead(t *testing.T) {
+	exec := createExecution(t)
+	buf := new(bytes.Buffer)
+	assert.NilError(t, Write(buf, exec, Config{ProjectName: "project"}))
+
+	suites, err := Read(buf)
+	assert.NilError(t, err)
+	expected := generate(exec, configWithDefaults(Config{ProjectName: "project"}))
+	assert.Equal(t, suites.Name, expected.Name)
+	assert.Equal(t, len(suites.Suites), len(expected.Suites))
+	for i, suite := range suites.Suites {
+		assert.Equal(t, suite.Name, expected.Suites[i].Name)
+		assert.Equal(t, len(suite.TestCases), len(expected.Suites[i].TestCases))
+	}
+}
+
+func TestReadSingleTestSuite(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<testsuite name="example" tests="2" time="1.5">
+	<testcase classname="example" name="TestOne" time="0.5"/>
+	<testcase classname="example" name="TestTwo" time="1.0">
+		<error message="boom">stack trace</error>
+	</testcase>
+</testsuite>`
+	suites, err := Read(strings.NewReader(doc))
+	assert.NilError(t, err)
+	assert.Equal(t, len(suites.Suites), 1)
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Name, "example")
+	assert.Equal(t, len(suite.TestCases), 2)
+	assert.Equal(t, suite.TestCases[1].Error.Contents, "stack trace")
+}
+
+func TestReadUnsupportedDocument(t *testing.T) {
+	_, err := Read(strings.NewReader(`<results><test name="one"/></results>`))
+	assert.Error(t, err, "unsupported JUnit XML: the root element is <results>, "+
+		"expected <testsuites> or <testsuite>")
+
+	_, err = Read(strings.NewReader(`<testsuites><testsuite name="a">`))
+	assert.ErrorContains(t, err, "failed to parse JUnit XML")
+
+	_, err = Read(strings.NewReader(""))
+	assert.Error(t, err, "failed to parse JUnit XML: no testsuites element")
+}
+
+func TestParseTime(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"":       0,
+		"0.020s": 20 * time.Millisecond,
+		"1.5":    1500 * time.Millisecond,
+		" 3 ":    3 * time.Second,
+	} {
+		actual, err := ParseTime(value)
+		assert.NilError(t, err, value)
+		assert.Equal(t, actual, expected, value)
+	}
+
+	_, err := ParseTime("1,5")
+	assert.Error(t, err, `invalid JUnit XML time "1,5"`)
+}
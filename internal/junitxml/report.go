@@ -5,8 +5,10 @@ package junitxml
 import (
 	"encoding/xml"
 	"io"
-	"path/filepath"
+	"path"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"gotest.tools/gotestsum/testjson"
@@ -14,8 +16,9 @@ import (
 
 // JUnitTestSuites is a collection of JUnit test suites.
 type JUnitTestSuites struct {
-	XMLName xml.Name `xml:"testsuites"`
-	Suites  []JUnitTestSuite
+	XMLName xml.Name         `xml:"testsuites"`
+	Name    string           `xml:"name,attr,omitempty"`
+	Suites  []JUnitTestSuite `xml:"testsuite"`
 }
 
 // JUnitTestSuite is a single JUnit test suite which may contain many
@@ -24,10 +27,12 @@ type JUnitTestSuite struct {
 	XMLName    xml.Name        `xml:"testsuite"`
 	Tests      int             `xml:"tests,attr"`
 	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr,omitempty"`
 	Time       string          `xml:"time,attr"`
 	Name       string          `xml:"name,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
 	Properties []JUnitProperty `xml:"properties>property,omitempty"`
-	TestCases  []JUnitTestCase
+	TestCases  []JUnitTestCase `xml:"testcase"`
 }
 
 // JUnitTestCase is a single test case with its result.
@@ -38,6 +43,15 @@ type JUnitTestCase struct {
 	Time        string            `xml:"time,attr"`
 	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
 	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	// Error is an unexpected error in the test case. It is only set by
+	// WriteStartupError, but may be set in the XML read from other tools.
+	Error *JUnitFailure `xml:"error,omitempty"`
+	// FlakyFailures are the failed attempts of a test which passed when it
+	// was run again.
+	FlakyFailures []JUnitFailure `xml:"flakyFailure,omitempty"`
+	// RerunFailures are the failed attempts, before the last attempt, of a
+	// test which failed every time it was run.
+	RerunFailures []JUnitFailure `xml:"rerunFailure,omitempty"`
 }
 
 // JUnitSkipMessage contains the reason why a testcase was skipped.
@@ -58,41 +72,144 @@ type JUnitFailure struct {
 	Contents string `xml:",chardata"`
 }
 
+// Config used to write a JUnit XML document.
+type Config struct {
+	// FormatTestSuiteName formats the package path used for the name of a
+	// testsuite. Defaults to the full package path.
+	FormatTestSuiteName FormatFunc
+	// FormatTestCaseClassname formats the package path used for the
+	// classname of a testcase. Defaults to the last element of the package
+	// path.
+	FormatTestCaseClassname FormatFunc
+	// ProjectName is the name of the testsuites element. The attribute is
+	// omitted when the name is empty.
+	ProjectName string
+	// HideEmptyPackages omits the testsuite of packages which have no
+	// testcases.
+	HideEmptyPackages bool
+	// Properties are added to the properties of every testsuite, after the
+	// go.version property.
+	Properties []JUnitProperty
+}
+
+// FormatFunc converts a package path into the value of a JUnit attribute.
+type FormatFunc func(pkgpath string) string
+
+func configWithDefaults(cfg Config) Config {
+	if cfg.FormatTestSuiteName == nil {
+		cfg.FormatTestSuiteName = func(pkgpath string) string { return pkgpath }
+	}
+	if cfg.FormatTestCaseClassname == nil {
+		cfg.FormatTestCaseClassname = path.Base
+	}
+	return cfg
+}
+
 // Write creates an XML document and writes it to out.
-func Write(out io.Writer, exec *testjson.Execution) error {
-	return errors.Wrap(write(out, generate(exec)), "failed to write JUnit XML")
+func Write(out io.Writer, exec *testjson.Execution, cfg Config) error {
+	doc := generate(exec, configWithDefaults(cfg))
+	return errors.Wrap(write(out, doc), "failed to write JUnit XML")
+}
+
+// WriteStartupError creates an XML document with a single testsuite, which
+// reports the error that prevented go test from starting, and writes it to
+// out. The document lets CI show why there are no test results.
+func WriteStartupError(out io.Writer, startErr error, cfg Config) error {
+	return AppendStartupError(out, JUnitTestSuites{}, startErr, cfg)
+}
+
+// AppendStartupError creates an XML document with the testsuites of existing,
+// followed by the testsuite which reports startErr, and writes it to out.
+func AppendStartupError(
+	out io.Writer,
+	existing JUnitTestSuites,
+	startErr error,
+	cfg Config,
+) error {
+	cfg = configWithDefaults(cfg)
+	doc := JUnitTestSuites{
+		Name: cfg.ProjectName,
+		Suites: append(existing.Suites, JUnitTestSuite{
+			Name:       "gotestsum",
+			Timestamp:  formatTimestamp(time.Now()),
+			Tests:      1,
+			Errors:     1,
+			Time:       "0.000",
+			Properties: packageProperties(cfg.Properties),
+			TestCases: []JUnitTestCase{{
+				Classname: "gotestsum",
+				Name:      "startup",
+				Time:      "0.000",
+				Error: &JUnitFailure{
+					Message:  "go test failed to start",
+					Type:     "StartupError",
+					Contents: startErr.Error(),
+				},
+			}},
+		}),
+	}
+	if doc.Name == "" {
+		doc.Name = existing.Name
+	}
+	return errors.Wrap(write(out, doc), "failed to write JUnit XML")
+}
+
+// Append creates an XML document with the testsuites of existing, followed by
+// the testsuites of exec, and writes it to out. Use Read to parse an existing
+// document. An empty existing is the same as calling Write.
+func Append(out io.Writer, existing JUnitTestSuites, exec *testjson.Execution, cfg Config) error {
+	doc := generate(exec, configWithDefaults(cfg))
+	if doc.Name == "" {
+		doc.Name = existing.Name
+	}
+	doc.Suites = append(existing.Suites, doc.Suites...)
+	return errors.Wrap(write(out, doc), "failed to write JUnit XML")
 }
 
-func generate(exec *testjson.Execution) JUnitTestSuites {
-	suites := JUnitTestSuites{}
+func generate(exec *testjson.Execution, cfg Config) JUnitTestSuites {
+	suites := JUnitTestSuites{Name: cfg.ProjectName}
 	for _, pkgname := range exec.Packages() {
 		pkg := exec.Package(pkgname)
+		testCases := packageTestCases(pkg, cfg.FormatTestCaseClassname)
+		if cfg.HideEmptyPackages && len(testCases) == 0 {
+			continue
+		}
 		junitpkg := JUnitTestSuite{
-			Name:       pkgname,
-			Tests:      pkg.Total,
+			Name:       cfg.FormatTestSuiteName(pkgname),
+			Timestamp:  formatTimestamp(pkg.Started()),
+			Tests:      pkg.Total - countReruns(testCases),
 			Time:       testjson.FormatDurationAsSeconds(pkg.Elapsed(), 3),
-			Properties: packageProperties(),
-			TestCases:  packageTestCases(pkg),
-			Failures:   len(pkg.Failed),
+			Properties: packageProperties(cfg.Properties),
+			TestCases:  testCases,
+			Failures:   len(pkg.Failed) - countReruns(testCases),
 		}
 		suites.Suites = append(suites.Suites, junitpkg)
 	}
 	return suites
 }
 
-func packageProperties() []JUnitProperty {
-	return []JUnitProperty{
-		{Name: "go.version", Value: runtime.Version()},
+// formatTimestamp formats t as an ISO-8601 timestamp in UTC. An empty string
+// is returned for the zero time.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.UTC().Format(time.RFC3339)
 }
 
-func packageTestCases(pkg *testjson.Package) []JUnitTestCase {
+func packageProperties(extra []JUnitProperty) []JUnitProperty {
+	return append([]JUnitProperty{
+		{Name: "go.version", Value: runtime.Version()},
+	}, extra...)
+}
+
+func packageTestCases(pkg *testjson.Package, formatClassname FormatFunc) []JUnitTestCase {
 	cases := []JUnitTestCase{}
 
 	if pkg.TestMainFailed() {
 		jtc := newJUnitTestCase(testjson.TestCase{
 			Test: "TestMain",
-		})
+		}, formatClassname)
 		jtc.Failure = &JUnitFailure{
 			Message:  "Failed",
 			Contents: pkg.Output(""),
@@ -100,31 +217,102 @@ func packageTestCases(pkg *testjson.Package) []JUnitTestCase {
 		cases = append(cases, jtc)
 	}
 
-	for _, tc := range pkg.Failed {
-		jtc := newJUnitTestCase(tc)
-		jtc.Failure = &JUnitFailure{
-			Message:  "Failed",
-			Contents: pkg.Output(tc.Test),
+	groups := groupFailedAttempts(pkg.Failed)
+	flaky := flakyAttempts(pkg.Passed, groups)
+	for i, failed := range groups {
+		if j, ok := flaky[failed[0].Test]; ok && i == j {
+			// flaky tests are reported with the attempt which passed
+			continue
 		}
+		last := failed[len(failed)-1]
+		jtc := newJUnitTestCase(last, formatClassname)
+		jtc.Failure = newJUnitFailure(pkg, last)
+		jtc.RerunFailures = junitFailures(pkg, failed[:len(failed)-1])
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Skipped {
-		jtc := newJUnitTestCase(tc)
-		jtc.SkipMessage = &JUnitSkipMessage{Message: pkg.Output(tc.Test)}
+		jtc := newJUnitTestCase(tc, formatClassname)
+		jtc.SkipMessage = &JUnitSkipMessage{
+			Message: strings.Join(pkg.OutputLines(tc), ""),
+		}
 		cases = append(cases, jtc)
 	}
 
 	for _, tc := range pkg.Passed {
-		jtc := newJUnitTestCase(tc)
+		jtc := newJUnitTestCase(tc, formatClassname)
+		if i, ok := flaky[tc.Test]; ok && tc.Run() > 1 {
+			jtc.FlakyFailures = junitFailures(pkg, groups[i])
+			delete(flaky, tc.Test)
+		}
 		cases = append(cases, jtc)
 	}
 	return cases
 }
 
-func newJUnitTestCase(tc testjson.TestCase) JUnitTestCase {
+// groupFailedAttempts returns the failed test cases grouped by attempt, in the
+// order of their first failure. A test case from the first run starts a new
+// group, so that each failure of a test run with -count=N is reported on its
+// own. A test case from a rerun of the failed tests is added to the last group
+// of the test with the same name.
+func groupFailedAttempts(failed []testjson.TestCase) [][]testjson.TestCase {
+	var groups [][]testjson.TestCase
+	last := make(map[string]int)
+	for _, tc := range failed {
+		if i, ok := last[tc.Test]; ok && tc.Run() > 1 {
+			groups[i] = append(groups[i], tc)
+			continue
+		}
+		last[tc.Test] = len(groups)
+		groups = append(groups, []testjson.TestCase{tc})
+	}
+	return groups
+}
+
+// flakyAttempts returns the index of the group of failed attempts of each test
+// which passed when it was rerun, keyed by the name of the test.
+func flakyAttempts(passed []testjson.TestCase, groups [][]testjson.TestCase) map[string]int {
+	last := make(map[string]int)
+	for i, group := range groups {
+		last[group[0].Test] = i
+	}
+	flaky := make(map[string]int)
+	for _, tc := range passed {
+		if i, ok := last[tc.Test]; ok && tc.Run() > 1 {
+			flaky[tc.Test] = i
+		}
+	}
+	return flaky
+}
+
+func newJUnitFailure(pkg *testjson.Package, tc testjson.TestCase) *JUnitFailure {
+	return &JUnitFailure{
+		Message:  "Failed",
+		Contents: strings.Join(pkg.OutputLines(tc), ""),
+	}
+}
+
+func junitFailures(pkg *testjson.Package, attempts []testjson.TestCase) []JUnitFailure {
+	var failures []JUnitFailure
+	for _, tc := range attempts {
+		failures = append(failures, *newJUnitFailure(pkg, tc))
+	}
+	return failures
+}
+
+// countReruns returns the number of failed attempts which are reported as
+// flaky or rerun failures, instead of as test cases.
+func countReruns(cases []JUnitTestCase) int {
+	var count int
+	for _, jtc := range cases {
+		count += len(jtc.FlakyFailures) + len(jtc.RerunFailures)
+	}
+	return count
+}
+
+func newJUnitTestCase(tc testjson.TestCase, formatClassname FormatFunc) JUnitTestCase {
 	return JUnitTestCase{
-		Classname: filepath.Base(tc.Package),
+		Classname: formatClassname(tc.Package),
 		Name:      tc.Test,
 		Time:      testjson.FormatDurationAsSeconds(tc.Elapsed, 3),
 	}
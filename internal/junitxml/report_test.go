@@ -2,10 +2,15 @@ package junitxml
 
 import (
 	"bytes"
+	"encoding/xml"
 	"io"
 	"io/ioutil"
+	"path"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	"gotest.tools/assert"
 	"gotest.tools/golden"
 	"gotest.tools/gotestsum/testjson"
@@ -15,11 +20,147 @@ func TestWrite(t *testing.T) {
 	out := new(bytes.Buffer)
 	exec := createExecution(t)
 
-	err := Write(out, exec)
+	err := Write(out, exec, Config{})
 	assert.NilError(t, err)
 	golden.Assert(t, out.String(), "junitxml-report.golden")
 }
 
+func TestGenerateWithFormatFuncs(t *testing.T) {
+	exec := createExecution(t)
+	cfg := configWithDefaults(Config{
+		FormatTestSuiteName: func(pkgpath string) string {
+			return strings.TrimPrefix(pkgpath, "github.com/gotestyourself/")
+		},
+		FormatTestCaseClassname: func(pkgpath string) string {
+			return "class." + path.Base(pkgpath)
+		},
+	})
+	suites := generate(exec, cfg)
+
+	var names []string
+	for _, suite := range suites.Suites {
+		names = append(names, suite.Name)
+	}
+	expected := []string{
+		"gotestyourself/testjson/internal/badmain",
+		"gotestyourself/testjson/internal/good",
+		"gotestyourself/testjson/internal/stub",
+	}
+	assert.DeepEqual(t, names, expected)
+	assert.Equal(t, suites.Suites[1].TestCases[0].Classname, "class.good")
+}
+
+func TestGenerateWithProjectName(t *testing.T) {
+	exec := createExecution(t)
+	suites := generate(exec, configWithDefaults(Config{ProjectName: "gotestsum"}))
+	assert.Equal(t, suites.Name, "gotestsum")
+}
+
+func TestGenerateWithHideEmptyPackages(t *testing.T) {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(`{"Action":"run","Package":"example.com/good","Test":"TestOk"}
+{"Action":"pass","Package":"example.com/good","Test":"TestOk","Elapsed":0.01}
+{"Action":"pass","Package":"example.com/good","Elapsed":0.01}
+{"Action":"skip","Package":"example.com/empty","Elapsed":0}
+`),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	suites := generate(exec, configWithDefaults(Config{}))
+	assert.Equal(t, len(suites.Suites), 2)
+
+	suites = generate(exec, configWithDefaults(Config{HideEmptyPackages: true}))
+	assert.Equal(t, len(suites.Suites), 1)
+	assert.Equal(t, suites.Suites[0].Name, "example.com/good")
+}
+
+func TestGenerateWithReruns(t *testing.T) {
+	exec := testjson.NewExecution()
+	runs := []string{
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestFlaky","Output":"flaky 1\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"broken 1\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOk"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOk"}
+{"Action":"fail","Package":"example.com/pkg"}
+`,
+		`{"Action":"run","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestFlaky"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestBroken","Output":"broken 2\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestBroken"}
+{"Action":"fail","Package":"example.com/pkg"}
+`,
+	}
+	for _, run := range runs {
+		_, err := testjson.ScanTestOutput(testjson.ScanConfig{
+			Stdout:    strings.NewReader(run),
+			Stderr:    strings.NewReader(""),
+			Handler:   &noopHandler{},
+			Execution: exec,
+		})
+		assert.NilError(t, err)
+	}
+
+	suites := generate(exec, configWithDefaults(Config{}))
+	assert.Equal(t, len(suites.Suites), 1)
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 3)
+	assert.Equal(t, suite.Failures, 1)
+	assert.Equal(t, len(suite.TestCases), 3)
+
+	broken := suite.TestCases[0]
+	assert.Equal(t, broken.Name, "TestBroken")
+	assert.Equal(t, broken.Failure.Contents, "broken 2\n")
+	assert.DeepEqual(t, broken.RerunFailures,
+		[]JUnitFailure{{Message: "Failed", Contents: "broken 1\n"}})
+	assert.Assert(t, broken.FlakyFailures == nil)
+
+	flaky := suite.TestCases[2]
+	assert.Equal(t, flaky.Name, "TestFlaky")
+	assert.Assert(t, flaky.Failure == nil)
+	assert.DeepEqual(t, flaky.FlakyFailures,
+		[]JUnitFailure{{Message: "Failed", Contents: "flaky 1\n"}})
+}
+
+func TestGenerateWithCount(t *testing.T) {
+	// the output of go test -count=3, the failures are not reruns
+	run := `{"Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"a 1\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestA","Output":"a 2\n"}
+{"Action":"fail","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestA"}
+{"Action":"fail","Package":"example.com/pkg"}
+`
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  strings.NewReader(run),
+		Stderr:  strings.NewReader(""),
+		Handler: &noopHandler{},
+	})
+	assert.NilError(t, err)
+
+	suites := generate(exec, configWithDefaults(Config{}))
+	suite := suites.Suites[0]
+	assert.Equal(t, suite.Tests, 3)
+	assert.Equal(t, suite.Failures, 2)
+	assert.Equal(t, len(suite.TestCases), 3)
+	for _, jtc := range suite.TestCases {
+		assert.Assert(t, jtc.RerunFailures == nil)
+		assert.Assert(t, jtc.FlakyFailures == nil)
+	}
+	assert.Assert(t, suite.TestCases[0].Failure != nil)
+	assert.Assert(t, suite.TestCases[1].Failure != nil)
+	assert.Assert(t, suite.TestCases[2].Failure == nil)
+}
+
 func createExecution(t *testing.T) *testjson.Execution {
 	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
 		Stdout:  readTestData(t, "out"),
@@ -45,3 +186,75 @@ func (s *noopHandler) Event(testjson.TestEvent, *testjson.Execution) error {
 func (s *noopHandler) Err(string) error {
 	return nil
 }
+
+func TestAppend(t *testing.T) {
+	exec := createExecution(t)
+	first := new(bytes.Buffer)
+	assert.NilError(t, Write(first, exec, Config{ProjectName: "matrix"}))
+	existing, err := Read(first)
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Append(out, existing, exec, Config{}))
+
+	var doc JUnitTestSuites
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, doc.Name, "matrix")
+	expected := len(generate(exec, configWithDefaults(Config{})).Suites)
+	assert.Equal(t, len(doc.Suites), 2*expected)
+}
+
+func TestAppendEmptyExisting(t *testing.T) {
+	exec := createExecution(t)
+	expected := new(bytes.Buffer)
+	assert.NilError(t, Write(expected, exec, Config{}))
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, Append(out, JUnitTestSuites{}, exec, Config{}))
+	assert.Equal(t, out.String(), expected.String())
+}
+
+func TestGenerateWithProperties(t *testing.T) {
+	exec := createExecution(t)
+	properties := []JUnitProperty{
+		{Name: "git.commit", Value: "abc123"},
+		{Name: "build.number", Value: "42"},
+	}
+	suites := generate(exec, configWithDefaults(Config{Properties: properties}))
+	assert.Assert(t, len(suites.Suites) > 0)
+	for _, suite := range suites.Suites {
+		expected := append([]JUnitProperty{{Name: "go.version", Value: runtime.Version()}},
+			properties...)
+		assert.DeepEqual(t, suite.Properties, expected)
+	}
+}
+
+func TestWriteStartupError(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := WriteStartupError(out, errors.New(`exec: "go": not found`), Config{})
+	assert.NilError(t, err)
+
+	var doc JUnitTestSuites
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, len(doc.Suites), 1)
+	suite := doc.Suites[0]
+	assert.Equal(t, suite.Errors, 1)
+	assert.Equal(t, suite.Tests, 1)
+	assert.Equal(t, len(suite.TestCases), 1)
+	assert.Assert(t, suite.TestCases[0].Error != nil)
+	assert.Equal(t, suite.TestCases[0].Error.Contents, `exec: "go": not found`)
+}
+
+func TestAppendStartupError(t *testing.T) {
+	existing := JUnitTestSuites{Name: "project", Suites: []JUnitTestSuite{{Name: "previous"}}}
+	out := new(bytes.Buffer)
+	err := AppendStartupError(out, existing, errors.New("failed"), Config{})
+	assert.NilError(t, err)
+
+	var doc JUnitTestSuites
+	assert.NilError(t, xml.Unmarshal(out.Bytes(), &doc))
+	assert.Equal(t, doc.Name, "project")
+	assert.Equal(t, len(doc.Suites), 2)
+	assert.Equal(t, doc.Suites[0].Name, "previous")
+	assert.Equal(t, doc.Suites[1].Errors, 1)
+}
@@ -0,0 +1,175 @@
+// Package mdsummary creates a Markdown summary report from a
+// testjson.Execution, which can be posted as a comment on a pull request.
+package mdsummary
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// report is the data used to write the Markdown document.
+type report struct {
+	total    int
+	passed   int
+	failed   int
+	skipped  int
+	elapsed  time.Duration
+	packages []packageRow
+	failures []failure
+	errors   []string
+	// packagesFailed is the number of packages which failed without a test
+	// failure. They are not counted in failed, which is a count of tests.
+	packagesFailed int
+}
+
+// packageRow is a row of the table of package results.
+type packageRow struct {
+	name    string
+	result  testjson.Action
+	passed  int
+	failed  int
+	skipped int
+	elapsed time.Duration
+}
+
+// failure is a failed test, or a package which failed without a test failure.
+type failure struct {
+	name    string
+	elapsed time.Duration
+	output  string
+}
+
+// Write creates a Markdown summary document and writes it to out.
+func Write(out io.Writer, exec *testjson.Execution) error {
+	return errors.Wrap(write(out, generate(exec)), "failed to write Markdown summary")
+}
+
+func generate(exec *testjson.Execution) report {
+	r := report{
+		total:   exec.Total(),
+		skipped: len(exec.Skipped()),
+		elapsed: exec.Elapsed(),
+		errors:  exec.Errors(),
+	}
+	for _, name := range exec.Packages() {
+		pkg := exec.Package(name)
+		r.passed += len(pkg.Passed)
+		r.packages = append(r.packages, packageRow{
+			name:    testjson.RelativePackagePath(name),
+			result:  pkg.Result(),
+			passed:  len(pkg.Passed),
+			failed:  len(pkg.Failed),
+			skipped: len(pkg.Skipped),
+			elapsed: pkg.Elapsed(),
+		})
+	}
+	for _, tc := range exec.Failed() {
+		r.failures = append(r.failures, failure{
+			name:    strings.TrimSpace(testjson.RelativePackagePath(tc.Package) + " " + tc.Test),
+			elapsed: tc.Elapsed,
+			output:  strings.Join(exec.Package(tc.Package).OutputLines(tc), ""),
+		})
+		if tc.Test == "" {
+			r.packagesFailed++
+			continue
+		}
+		r.failed++
+	}
+	return r
+}
+
+func write(out io.Writer, r report) error {
+	buf := new(strings.Builder)
+	fmt.Fprintf(buf, "## Test summary\n\n")
+	fmt.Fprintf(buf, "**%s**, %d passed, %d failed, %d skipped, %s%s in %s\n",
+		pluralize(r.total, "test"), r.passed, r.failed, r.skipped,
+		formatPackagesFailed(r.packagesFailed),
+		pluralize(len(r.errors), "error"),
+		testjson.FormatDurationAsSeconds(r.elapsed, 3))
+	writePackageTable(buf, r.packages)
+	writeFailures(buf, r.failures)
+	if len(r.errors) > 0 {
+		fmt.Fprintf(buf, "\n### Errors\n\n%s", codeBlock(strings.Join(r.errors, "\n")+"\n"))
+	}
+	_, err := io.WriteString(out, buf.String())
+	return err
+}
+
+// formatPackagesFailed returns the count of packages which failed without a
+// test failure, or an empty string when there are none.
+func formatPackagesFailed(count int) string {
+	if count == 0 {
+		return ""
+	}
+	return pluralize(count, "package") + " failed, "
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+func writePackageTable(out io.Writer, packages []packageRow) {
+	if len(packages) == 0 {
+		return
+	}
+	fmt.Fprint(out, "\n| Package | Result | Passed | Failed | Skipped | Time |\n")
+	fmt.Fprint(out, "|---|---|---:|---:|---:|---:|\n")
+	for _, pkg := range packages {
+		fmt.Fprintf(out, "| `%s` | %s | %d | %d | %d | %s |\n",
+			escapeTableCell(pkg.name), formatResult(pkg.result),
+			pkg.passed, pkg.failed, pkg.skipped,
+			testjson.FormatDurationAsSeconds(pkg.elapsed, 3))
+	}
+}
+
+func formatResult(result testjson.Action) string {
+	switch result {
+	case testjson.ActionPass:
+		return "✅ pass"
+	case testjson.ActionFail:
+		return "❌ fail"
+	default:
+		return "➖ no tests"
+	}
+}
+
+// writeFailures prints the output of each failure in a details element, so
+// that the output is collapsed until it is opened.
+func writeFailures(out io.Writer, failures []failure) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprint(out, "\n### Failures\n")
+	for _, f := range failures {
+		fmt.Fprintf(out, "\n<details>\n<summary>%s (%s)</summary>\n\n%s\n</details>\n",
+			html.EscapeString(f.name),
+			testjson.FormatDurationAsSeconds(f.elapsed, 2),
+			codeBlock(f.output))
+	}
+}
+
+// codeBlock returns text in a fenced code block. The fence is longer than any
+// run of backticks in the text, so that the text can not end the block.
+func codeBlock(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return fence + "\n" + text + fence + "\n"
+}
+
+func escapeTableCell(value string) string {
+	return strings.Replace(value, "|", `\|`, -1)
+}
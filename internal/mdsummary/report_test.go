@@ -0,0 +1,26 @@
+package mdsummary
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/golden"
+	"gotest.tools/gotestsum/internal/testexec"
+)
+
+func TestWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	summary := generate(testexec.ScanTestData(t))
+	// elapsed is the wall clock time of the test run, which is not stable
+	summary.elapsed = 0
+
+	err := write(out, summary)
+	assert.NilError(t, err)
+	golden.Assert(t, out.String(), "mdsummary-report.golden")
+}
+
+func TestCodeBlock(t *testing.T) {
+	assert.Equal(t, codeBlock("one\n"), "```\none\n```\n")
+	assert.Equal(t, codeBlock("a ``` b"), "````\na ``` b\n````\n")
+}
@@ -0,0 +1,60 @@
+// Package testexec creates a testjson.Execution for the tests of the packages
+// which write reports from an Execution.
+package testexec
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Scan returns the Execution of the test2json events read from stdout.
+func Scan(t *testing.T, stdout io.Reader) *testjson.Execution {
+	return scan(t, stdout, strings.NewReader(""))
+}
+
+// ScanTestData returns the Execution of the go-test-json.out and
+// go-test-json.err files in testjson/testdata.
+func ScanTestData(t *testing.T) *testjson.Execution {
+	return scan(t, ReadTestData(t, "out"), ReadTestData(t, "err"))
+}
+
+func scan(t *testing.T, stdout, stderr io.Reader) *testjson.Execution {
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Handler: NoopHandler{},
+	})
+	assert.NilError(t, err)
+	return exec
+}
+
+// ReadTestData returns the go-test-json file for stream, either out or err,
+// from testjson/testdata.
+func ReadTestData(t *testing.T, stream string) io.Reader {
+	_, filename, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(filename), "../../testjson/testdata/go-test-json."+stream)
+	raw, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	return bytes.NewReader(raw)
+}
+
+// NoopHandler is a testjson.EventHandler which ignores every event.
+type NoopHandler struct{}
+
+// Event does nothing.
+func (NoopHandler) Event(testjson.TestEvent, *testjson.Execution) error {
+	return nil
+}
+
+// Err does nothing.
+func (NoopHandler) Err(string) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/fatih/color"
+)
+
+// coveragePattern matches the coverage printed by go test -cover.
+var coveragePattern = regexp.MustCompile(`coverage: (\d+(?:\.\d+)?)% of statements`)
+
+// parseCoverage returns the coverage percentage from a line of package output,
+// or false if the line does not include the coverage.
+func parseCoverage(output string) (float64, bool) {
+	match := coveragePattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(match[1], 64)
+	return value, err == nil
+}
+
+// Coverage returns the percentage of statements covered by the tests of the
+// package, and true, or false if go test did not print the coverage.
+func (p Package) Coverage() (float64, bool) {
+	return p.coverage, p.hasCoverage
+}
+
+// writeCoverageSummary prints the coverage of each package which printed a
+// coverage, followed by the unweighted mean of the coverage of those packages.
+// The mean is not weighted by the number of statements in each package, because
+// go test does not print the number of statements.
+func writeCoverageSummary(out io.Writer, execution *Execution) {
+	var names []string
+	var width int
+	for _, name := range execution.Packages() {
+		if _, ok := execution.Package(name).Coverage(); !ok {
+			continue
+		}
+		names = append(names, name)
+		if len(RelativePackagePath(name)) > width {
+			width = len(RelativePackagePath(name))
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.CyanString("\n=== Coverage"))
+	var total float64
+	for _, name := range names {
+		coverage, _ := execution.Package(name).Coverage()
+		total += coverage
+		fmt.Fprintf(out, "%-*s %5.1f%%\n", width, RelativePackagePath(name), coverage)
+	}
+	fmt.Fprintf(out, "unweighted mean of the package coverage %.1f%% in %s\n",
+		total/float64(len(names)), pluralize(len(names), "package"))
+}
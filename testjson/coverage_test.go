@@ -0,0 +1,48 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintSummaryWithCoverage(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := &Execution{started: fake.Now(), packages: map[string]*Package{}}
+	for _, event := range []TestEvent{
+		{Action: ActionOutput, Package: "example.com/a", Output: "coverage: 85.7% of statements\n"},
+		{Action: ActionPass, Package: "example.com/a"},
+		{Action: ActionOutput, Package: "example.com/long/name",
+			Output: "ok  \texample.com/long/name\t0.01s\tcoverage: 3.0% of statements\n"},
+		{Action: ActionPass, Package: "example.com/long/name"},
+		{Action: ActionOutput, Package: "example.com/none", Output: "coverage: [no statements]\n"},
+		{Action: ActionPass, Package: "example.com/none"},
+	} {
+		exec.add(event)
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSummary(out, exec, SummarizeCoverage))
+
+	expected := `
+=== Coverage
+a          85.7%
+long/name   3.0%
+unweighted mean of the package coverage 44.4% in 2 packages
+
+DONE 0 tests in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestParseCoverage(t *testing.T) {
+	coverage, ok := parseCoverage("coverage: 100.0% of statements in ./...\n")
+	assert.Assert(t, ok)
+	assert.Equal(t, coverage, 100.0)
+
+	_, ok = parseCoverage("coverage: [no statements]\n")
+	assert.Assert(t, !ok)
+}
@@ -0,0 +1,56 @@
+package testjson
+
+import (
+	"regexp"
+	"strings"
+)
+
+// groupTestCases groups test cases with the same normalized output. Groups
+// are returned in the order of the first test case in each group. Test cases
+// with no output are never grouped.
+func groupTestCases(
+	execution *Execution,
+	testCases []TestCase,
+	conf testCaseFormatConfig,
+) [][]TestCase {
+	var groups [][]TestCase
+	index := make(map[string]int)
+	for _, tc := range testCases {
+		key := normalizeOutput(execution.Package(tc.Package).OutputLines(tc), tc, conf)
+		if i, ok := index[key]; ok && key != "" {
+			groups[i] = append(groups[i], tc)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []TestCase{tc})
+	}
+	return groups
+}
+
+var (
+	lineNumberPattern = regexp.MustCompile(`\.go:\d+`)
+	addressPattern    = regexp.MustCompile(`0x[0-9a-f]+`)
+	goroutinePattern  = regexp.MustCompile(`goroutine \d+`)
+	durationPattern   = regexp.MustCompile(`\(\d+\.\d+s\)`)
+)
+
+// normalizeOutput returns the output of a test case with the parts that are
+// expected to be different for the same failure, like test names, line
+// numbers, and memory addresses, removed.
+func normalizeOutput(lines []string, tc TestCase, conf testCaseFormatConfig) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if isRunLine(line) || conf.filter(line) {
+			continue
+		}
+		if tc.Test != "" {
+			line = strings.Replace(line, tc.Test, "", -1)
+		}
+		line = lineNumberPattern.ReplaceAllString(line, ".go:N")
+		line = addressPattern.ReplaceAllString(line, "0x?")
+		line = goroutinePattern.ReplaceAllString(line, "goroutine N")
+		line = durationPattern.ReplaceAllString(line, "")
+		b.WriteString(strings.TrimSpace(line) + "\n")
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,66 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintSummaryWithDedupeFailures(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	panicOutput := func(name string, goroutine string, line string) []string {
+		return multiLine(`=== RUN   ` + name + `
+--- FAIL: ` + name + ` (0.00s)
+panic: runtime error: invalid memory address [recovered]
+goroutine ` + goroutine + ` [running]:
+example.com/one.helper(0x0)
+	/src/one/helper.go:` + line + ` +0x1d
+example.com/one.` + name + `(0xc0000b2100)
+`)
+	}
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total: 3,
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestA"},
+					{Package: "example.com/one", Test: "TestB"},
+					{Package: "example.com/one", Test: "TestC"},
+				},
+				output: map[string][]string{
+					"TestA": panicOutput("TestA", "7", "12"),
+					"TestB": panicOutput("TestB", "9", "12"),
+					"TestC": multiLine("=== RUN   TestC\n    one_test.go:40: wrong\n"),
+				},
+				action: ActionFail,
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	err := PrintSummary(out, exec, SummarizeFailed|SummarizeDedupeFailures)
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: one TestA (0.00s)
+panic: runtime error: invalid memory address [recovered]
+goroutine 7 [running]:
+example.com/one.helper(0x0)
+	/src/one/helper.go:12 +0x1d
+example.com/one.TestA(0xc0000b2100)
+
+=== FAIL: 1 more test with the same output: one TestB
+
+=== FAIL: one TestC (0.00s)
+    one_test.go:40: wrong
+
+
+DONE 3 tests, 3 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jonboulle/clockwork"
@@ -56,6 +57,7 @@ func (e TestEvent) ElapsedFormatted() string {
 }
 
 // Bytes returns the serialized JSON bytes that were parsed to create the event.
+// The bytes are a copy, which remains valid after the event is handled.
 func (e TestEvent) Bytes() []byte {
 	return e.raw
 }
@@ -68,10 +70,19 @@ type Package struct {
 	Skipped []TestCase
 	Passed  []TestCase
 	output  map[string][]string
+	// outputSize is the number of bytes of output stored for each test. It is
+	// only set when the output is limited by ScanConfig.MaxTestOutputBytes.
+	outputSize map[string]int
 	// action identifies if the package passed or failed. A package may fail
 	// with no test failures if an init() or TestMain exits non-zero.
 	// skip indicates there were no tests.
 	action Action
+	// started is the time of the first event for the package.
+	started time.Time
+	// coverage is the percentage of statements covered by the tests, when
+	// hasCoverage is true.
+	coverage    float64
+	hasCoverage bool
 }
 
 // Result returns if the package passed, failed, or was skipped because there
@@ -80,6 +91,12 @@ func (p Package) Result() Action {
 	return p.action
 }
 
+// Started returns the time of the first event for the package, or the zero
+// time if the events did not include a time.
+func (p Package) Started() time.Time {
+	return p.started
+}
+
 // Elapsed returns the sum of the elapsed time for all tests in the package.
 func (p Package) Elapsed() time.Duration {
 	elapsed := time.Duration(0)
@@ -105,11 +122,92 @@ func (p Package) TestMainFailed() bool {
 	return p.action == ActionFail && len(p.Failed) == 0
 }
 
+// OutputLines returns the output of a test case as an array of lines. When a
+// test is run more than once the output is the output of the run which
+// created the TestCase.
+func (p Package) OutputLines(tc TestCase) []string {
+	return p.output[outputKey(tc.Test, tc.run)]
+}
+
 // TestCase stores the name and elapsed time for a test case.
 type TestCase struct {
 	Package string
 	Test    string
 	Elapsed time.Duration
+	// run is the number of the run which produced the test case.
+	run int
+	// time is the time of the event which ended the test case.
+	time time.Time
+}
+
+// Run returns the number of the run which produced the test case. The first
+// run is 1, and each run scanned with ScanConfig.Execution, like a rerun of
+// failed tests, increments the number.
+func (tc TestCase) Run() int {
+	return tc.run
+}
+
+// Ended returns the time of the event which ended the test case, or the zero
+// time if the event did not include a time.
+func (tc TestCase) Ended() time.Time {
+	return tc.time
+}
+
+// outputKey returns the key used to store the output of a test. Output from
+// the first run is keyed by test name, subsequent runs of the same test are
+// stored separately so that the output from each run is preserved.
+func outputKey(test string, run int) string {
+	if run <= 1 {
+		return test
+	}
+	return fmt.Sprintf("%s#run%d", test, run)
+}
+
+// truncatedMarker is the first line of the output of a test when the start of
+// the output was removed because it exceeded ScanConfig.MaxTestOutputBytes.
+const truncatedMarker = "[output truncated]\n"
+
+// addOutput stores a line of output for the test identified by key. When max
+// is greater than 0 the output of the test is limited to max bytes.
+func (p *Package) addOutput(key string, output string, max int) {
+	lines := append(p.output[key], output)
+	if max <= 0 {
+		p.output[key] = lines
+		return
+	}
+	if p.outputSize == nil {
+		p.outputSize = make(map[string]int)
+	}
+	p.output[key], p.outputSize[key] = truncateOutput(lines, p.outputSize[key]+len(output), max)
+}
+
+// truncateOutput removes the oldest lines of output until the size of the
+// remaining lines is at most max bytes, because failure messages are usually
+// at the end of the output. A single line which is larger than max is cut to
+// the last max bytes. When lines are removed the output starts with
+// truncatedMarker, which is not included in the size.
+func truncateOutput(lines []string, size int, max int) ([]string, int) {
+	if size <= max {
+		return lines, size
+	}
+	start := 0
+	if lines[0] == truncatedMarker {
+		start = 1
+	}
+	for size > max && start < len(lines)-1 {
+		size -= len(lines[start])
+		start++
+	}
+	if size > max {
+		last := lines[len(lines)-1]
+		lines[len(lines)-1] = last[len(last)-max:]
+		size = max
+	}
+	if start == 0 {
+		return append([]string{truncatedMarker}, lines...), size
+	}
+	lines[start-1] = truncatedMarker
+	return lines[start-1:], size
 }
 
 func newPackage() *Package {
@@ -118,53 +216,69 @@ func newPackage() *Package {
 
 // Execution of one or more test packages
 type Execution struct {
-	started  time.Time
+	started time.Time
+	// finished is the time the last scan of test output ended.
+	finished time.Time
 	packages map[string]*Package
 	errors   []string
+	// buildFailures are the packages which failed to build, in the order the
+	// failures were reported.
+	buildFailures []string
+	runs          int
+	// maxOutputBytes limits the output stored for each test, see
+	// ScanConfig.MaxTestOutputBytes.
+	maxOutputBytes int
 }
 
 func (e *Execution) add(event TestEvent) {
 	pkg, ok := e.packages[event.Package]
 	if !ok {
 		pkg = newPackage()
+		pkg.started = event.Time
 		e.packages[event.Package] = pkg
 	}
+	key := outputKey(event.Test, e.runs)
 	if event.PackageEvent() {
-		switch event.Action {
-		case ActionPass, ActionFail:
-			pkg.action = event.Action
-		case ActionOutput:
-			pkg.output[""] = append(pkg.output[""], event.Output)
-		}
+		e.addPackageEvent(pkg, event, key)
 		return
 	}
 
+	tc := TestCase{
+		Package: event.Package,
+		Test:    event.Test,
+		Elapsed: elapsedDuration(event.Elapsed),
+		run:     e.runs,
+		time:    event.Time,
+	}
 	switch event.Action {
 	case ActionRun:
 		pkg.Total++
 	case ActionFail:
-		pkg.Failed = append(pkg.Failed, TestCase{
-			Package: event.Package,
-			Test:    event.Test,
-			Elapsed: elapsedDuration(event.Elapsed),
-		})
+		pkg.Failed = append(pkg.Failed, tc)
 	case ActionSkip:
-		pkg.Skipped = append(pkg.Skipped, TestCase{
-			Package: event.Package,
-			Test:    event.Test,
-			Elapsed: elapsedDuration(event.Elapsed),
-		})
+		pkg.Skipped = append(pkg.Skipped, tc)
 	case ActionOutput, ActionBench:
-		// TODO: limit size of buffered test output
-		pkg.output[event.Test] = append(pkg.output[event.Test], event.Output)
+		pkg.addOutput(key, event.Output, e.maxOutputBytes)
 	case ActionPass:
-		pkg.Passed = append(pkg.Passed, TestCase{
-			Package: event.Package,
-			Test:    event.Test,
-			Elapsed: elapsedDuration(event.Elapsed),
-		})
+		pkg.Passed = append(pkg.Passed, tc)
 		// Remove test output once a test passes, it wont be used
-		pkg.output[event.Test] = nil
+		pkg.output[key] = nil
+		delete(pkg.outputSize, key)
+	}
+}
+
+func (e *Execution) addPackageEvent(pkg *Package, event TestEvent, key string) {
+	switch event.Action {
+	case ActionPass, ActionFail:
+		pkg.action = event.Action
+	case ActionOutput:
+		pkg.addOutput(key, event.Output, e.maxOutputBytes)
+		if isBuildFailedOutput(event) {
+			e.addBuildFailure(event.Package)
+		}
+		if coverage, ok := parseCoverage(event.Output); ok {
+			pkg.coverage, pkg.hasCoverage = coverage, true
+		}
 	}
 }
 
@@ -172,14 +286,15 @@ func elapsedDuration(elapsed float64) time.Duration {
 	return time.Duration(elapsed*1000) * time.Millisecond
 }
 
-// Output returns the full test output for a test.
+// Output returns the full test output for a test from the most recent run.
 func (e *Execution) Output(pkg, test string) string {
-	return strings.Join(e.packages[pkg].output[test], "")
+	return strings.Join(e.OutputLines(pkg, test), "")
 }
 
-// OutputLines returns the full test output for a test as an array of lines.
+// OutputLines returns the full test output for a test from the most recent
+// run as an array of lines.
 func (e *Execution) OutputLines(pkg, test string) []string {
-	return e.packages[pkg].output[test]
+	return e.packages[pkg].output[outputKey(test, e.runs)]
 }
 
 // Package returns the Package by name.
@@ -194,9 +309,29 @@ func (e *Execution) Packages() []string {
 
 var clock = clockwork.NewRealClock()
 
-// Elapsed returns the time elapsed since the execution started.
+// Elapsed returns the wall clock time elapsed from the start of the execution
+// until ScanTestOutput finished reading the test output, or until now if the
+// output has not been scanned.
 func (e *Execution) Elapsed() time.Duration {
-	return clock.Now().Sub(e.started)
+	if e.finished.IsZero() {
+		return clock.Now().Sub(e.started)
+	}
+	return e.finished.Sub(e.started)
+}
+
+// TestTime returns the sum of the elapsed time of all the root tests. Subtests
+// are not included because their time is part of the time of the root test.
+// When tests run in parallel TestTime may be greater than Elapsed.
+func (e *Execution) TestTime() time.Duration {
+	var total time.Duration
+	for _, pkg := range e.packages {
+		for _, tc := range pkg.TestCases() {
+			if !strings.Contains(tc.Test, "/") {
+				total += tc.Elapsed
+			}
+		}
+	}
+	return total
 }
 
 // Failed returns a list of all the failed test cases.
@@ -233,6 +368,44 @@ func (e *Execution) Skipped() []TestCase {
 	return skipped
 }
 
+// BuildFailures returns the names of the packages which failed to build.
+func (e *Execution) BuildFailures() []string {
+	return e.buildFailures
+}
+
+func (e *Execution) addBuildFailure(pkg string) {
+	for _, name := range e.buildFailures {
+		if name == pkg {
+			return
+		}
+	}
+	e.buildFailures = append(e.buildFailures, pkg)
+}
+
+// isBuildFailedOutput returns true if the event is the output printed by go
+// test when the package, or the test binary for the package, failed to build.
+func isBuildFailedOutput(event TestEvent) bool {
+	return strings.HasPrefix(event.Output, "FAIL\t"+event.Package+" [build failed]")
+}
+
+// isNoTestFilesEvent returns true if the event is one of the events printed
+// by go test for a package which has no test files.
+func isNoTestFilesEvent(event TestEvent) bool {
+	if !event.PackageEvent() {
+		return false
+	}
+	return event.Action == ActionSkip ||
+		event.Action == ActionOutput && strings.HasSuffix(event.Output, "\t[no test files]\n")
+}
+
+// removeEmptyPackage removes the package if it was added by an earlier event,
+// and has no tests.
+func (e *Execution) removeEmptyPackage(name string) {
+	if pkg, ok := e.packages[name]; ok && pkg.Total == 0 {
+		delete(e.packages, name)
+	}
+}
+
 // Total returns a count of all test cases.
 func (e *Execution) Total() int {
 	total := 0
@@ -245,9 +418,12 @@ func (e *Execution) Total() int {
 func (e *Execution) addError(err string) {
 	// Build errors start with a header
 	if strings.HasPrefix(err, "# ") {
+		fields := strings.Fields(err)
+		if len(fields) > 1 {
+			e.addBuildFailure(fields[1])
+		}
 		return
 	}
-	// TODO: may need locking, or use a channel
 	e.errors = append(e.errors, err)
 }
 
@@ -256,6 +432,12 @@ func (e *Execution) Errors() []string {
 	return e.errors
 }
 
+// Runs returns the number of times ScanTestOutput was used to populate the
+// Execution. Runs is greater than 1 when failed tests were re-run.
+func (e *Execution) Runs() int {
+	return e.runs
+}
+
 // NewExecution returns a new Execution and records the current time as the
 // time the test execution started.
 func NewExecution() *Execution {
@@ -270,6 +452,19 @@ type ScanConfig struct {
 	Stdout  io.Reader
 	Stderr  io.Reader
 	Handler EventHandler
+	// Execution to populate while scanning. If Execution is nil a new one
+	// is created. An existing Execution may be used to add the events of
+	// subsequent runs to the results of a previous run.
+	Execution *Execution
+	// MaxTestOutputBytes limits the number of bytes of output stored for each
+	// test. When a test prints more, the start of the output is removed and
+	// replaced by an "[output truncated]" line. The default of 0 stores all
+	// the output.
+	MaxTestOutputBytes int
+	// IgnoreNonTestPackages removes the packages which have no test files. The
+	// events of those packages are not sent to the Handler, and the packages
+	// are not included in the Execution.
+	IgnoreNonTestPackages bool
 }
 
 // EventHandler is called by ScanTestOutput for each event and write to stderr.
@@ -280,24 +475,45 @@ type EventHandler interface {
 
 // ScanTestOutput reads lines from stdout and stderr, creates an Execution,
 // calls the Handler for each event, and returns the Execution.
+//
+// Lines of stdout which are not test2json events, like warnings from the go
+// toolchain, are handled in the same way as lines of stderr. They are passed
+// to Handler.Err unmodified, and included in Execution.Errors. The Handler is
+// never called concurrently for stdout and stderr.
 func ScanTestOutput(config ScanConfig) (*Execution, error) {
-	execution := NewExecution()
-	waitOnStderr := readStderr(config.Stderr, config.Handler.Err, execution)
+	execution := config.Execution
+	if execution == nil {
+		execution = NewExecution()
+	}
+	execution.runs++
+	if config.MaxTestOutputBytes > 0 {
+		execution.maxOutputBytes = config.MaxTestOutputBytes
+	}
+	var lock sync.Mutex
+	handleErr := func(text string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		execution.addError(text)
+		return config.Handler.Err(text)
+	}
+	waitOnStderr := readStderr(config.Stderr, handleErr)
 	scanner := bufio.NewScanner(config.Stdout)
 
 	for scanner.Scan() {
 		raw := scanner.Bytes()
-		event, err := parseEvent(raw)
-		switch err {
-		case errBadEvent:
-			// TODO: put raw into errors.
+		switch {
+		case len(bytes.TrimSpace(raw)) == 0:
+			continue
+		case !isEventLine(raw):
+			if err := handleErr(string(raw)); err != nil {
+				return nil, err
+			}
 			continue
-		case nil:
-		default:
-			return nil, errors.Wrapf(err, "failed to parse test output: %s", string(raw))
 		}
-		execution.add(event)
-		if err := config.Handler.Event(event, execution); err != nil {
+		lock.Lock()
+		err := scanEvent(config, execution, raw)
+		lock.Unlock()
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -306,18 +522,46 @@ func ScanTestOutput(config ScanConfig) (*Execution, error) {
 	if err := <-waitOnStderr; err != nil {
 		logrus.Warnf("failed reading stderr: %s", err)
 	}
+	execution.finished = clock.Now()
 	return execution, errors.Wrap(scanner.Err(), "failed to scan test output")
 }
 
+// isEventLine returns true if the line of stdout may be a test2json event. A
+// line which does not start with { is output from another program, and is
+// never parsed as an event. FAIL lines are handled by parseEvent.
+func isEventLine(raw []byte) bool {
+	line := bytes.TrimSpace(raw)
+	return line[0] == '{' || bytes.HasPrefix(line, []byte("FAIL"))
+}
+
+// scanEvent parses a line of stdout, adds the event to the execution, and
+// calls the Handler with the event.
+func scanEvent(config ScanConfig, execution *Execution, raw []byte) error {
+	event, err := parseEvent(raw)
+	switch err {
+	case errBadEvent:
+		// TODO: put raw into errors.
+		return nil
+	case nil:
+	default:
+		return errors.Wrapf(err, "failed to parse test output: %s", string(raw))
+	}
+	if config.IgnoreNonTestPackages && isNoTestFilesEvent(event) {
+		execution.removeEmptyPackage(event.Package)
+		return nil
+	}
+	execution.add(event)
+	return config.Handler.Event(event, execution)
+}
+
 type errHandler func(text string) error
 
-func readStderr(in io.Reader, handle errHandler, exec *Execution) chan error {
+func readStderr(in io.Reader, handle errHandler) chan error {
 	wait := make(chan error, 1)
 	go func() {
 		defer close(wait)
 		scanner := bufio.NewScanner(in)
 		for scanner.Scan() {
-			exec.addError(scanner.Text())
 			if err := handle(scanner.Text()); err != nil {
 				wait <- err
 				return
@@ -337,7 +581,8 @@ func parseEvent(raw []byte) (TestEvent, error) {
 
 	event := TestEvent{}
 	err := json.Unmarshal(raw, &event)
-	event.raw = raw
+	// raw is the buffer of the scanner, which is reused for the next line
+	event.raw = append([]byte(nil), raw...)
 	return event, err
 }
 
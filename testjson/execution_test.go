@@ -1,6 +1,9 @@
 package testjson
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,3 +25,197 @@ func TestPackage_Elapsed(t *testing.T) {
 	}
 	assert.Equal(t, pkg.Elapsed(), 3100*time.Millisecond)
 }
+
+func TestScanTestOutput_WithExistingExecution(t *testing.T) {
+	exec := NewExecution()
+	for i := 0; i < 2; i++ {
+		shim := newFakeHandler(standardQuietFormat, "go-test-json")
+		config := shim.Config(t)
+		config.Execution = exec
+
+		result, err := ScanTestOutput(config)
+		assert.NilError(t, err)
+		assert.Assert(t, result == exec)
+	}
+	assert.Equal(t, exec.Runs(), 2)
+	assert.Equal(t, exec.Total(), 2*(18+28))
+	pkg := exec.Package("github.com/gotestyourself/gotestyourself/testjson/internal/stub")
+	assert.Equal(t, len(pkg.Failed), 2*4)
+
+	// output from each run is stored separately
+	first, second := pkg.Failed[0], pkg.Failed[4]
+	assert.Equal(t, first.Test, second.Test)
+	assert.Assert(t, len(pkg.OutputLines(first)) > 0)
+	assert.DeepEqual(t, pkg.OutputLines(first), pkg.OutputLines(second))
+}
+
+func TestExecution_BuildFailures(t *testing.T) {
+	exec := NewExecution()
+	exec.addError("# example.com/broken [example.com/broken.test]")
+	exec.addError("broken/broken.go:5:21: undefined: somepackage")
+	exec.add(TestEvent{
+		Action:  ActionOutput,
+		Package: "example.com/other",
+		Output:  "FAIL\texample.com/other [build failed]\n",
+	})
+	exec.add(TestEvent{
+		Action:  ActionOutput,
+		Package: "example.com/broken",
+		Output:  "FAIL\texample.com/broken [build failed]\n",
+	})
+
+	expected := []string{"example.com/broken", "example.com/other"}
+	assert.DeepEqual(t, exec.BuildFailures(), expected)
+	assert.DeepEqual(t, exec.Errors(), []string{"broken/broken.go:5:21: undefined: somepackage"})
+}
+
+func TestExecution_MaxOutputBytes(t *testing.T) {
+	exec := NewExecution()
+	exec.maxOutputBytes = 10
+	output := func(text string) {
+		exec.add(TestEvent{
+			Action:  ActionOutput,
+			Package: "example.com/pkg",
+			Test:    "TestOne",
+			Output:  text,
+		})
+	}
+
+	output("first\n")
+	output("two\n")
+	assert.DeepEqual(t, exec.OutputLines("example.com/pkg", "TestOne"),
+		[]string{"first\n", "two\n"})
+
+	output("three\n")
+	assert.DeepEqual(t, exec.OutputLines("example.com/pkg", "TestOne"),
+		[]string{truncatedMarker, "two\n", "three\n"})
+
+	output("a very long line\n")
+	assert.DeepEqual(t, exec.OutputLines("example.com/pkg", "TestOne"),
+		[]string{truncatedMarker, "long line\n"})
+}
+
+func TestExecution_MaxOutputBytesSingleLine(t *testing.T) {
+	exec := NewExecution()
+	exec.maxOutputBytes = 5
+	exec.add(TestEvent{Action: ActionOutput, Package: "pkg", Test: "TestOne", Output: "panic: boom"})
+
+	assert.DeepEqual(t, exec.OutputLines("pkg", "TestOne"), []string{truncatedMarker, " boom"})
+}
+
+func TestScanTestOutput_IgnoreNonTestPackages(t *testing.T) {
+	input := `{"Action":"start","Package":"a/empty"}
+{"Action":"output","Package":"a/empty","Output":"?   \ta/empty\t[no test files]\n"}
+{"Action":"skip","Package":"a/empty","Elapsed":0}
+{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.01}
+`
+	shim := newFakeHandler(NewEventFormatter("short"), "")
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:                strings.NewReader(input),
+		Stderr:                strings.NewReader(""),
+		Handler:               shim,
+		IgnoreNonTestPackages: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, exec.Packages(), []string{"example.com/pkg"})
+	assert.Equal(t, shim.out.String(), "✓  example.com/pkg (10ms)\n")
+}
+
+func TestScanTestOutput_EventHandlerFunc(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.01}
+`
+	var actions []Action
+	var totals []int
+	handler := EventHandlerFunc(func(event TestEvent, execution *Execution) error {
+		actions = append(actions, event.Action)
+		totals = append(totals, execution.Total())
+		return nil
+	})
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Stderr:  strings.NewReader("some error\n"),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, actions, []Action{ActionRun, ActionPass, ActionPass})
+	assert.DeepEqual(t, totals, []int{1, 1, 1})
+	assert.DeepEqual(t, exec.Errors(), []string{"some error"})
+}
+
+func TestScanTestOutput_EventBytesAreKept(t *testing.T) {
+	input := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}` + "\n"
+	for i := 0; i < 100; i++ {
+		input += fmt.Sprintf(`{"Action":"output","Package":"example.com/pkg",`+
+			`"Test":"TestOne","Output":"line %d %s\n"}`+"\n", i, strings.Repeat("x", 60))
+	}
+	input += `{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0}` + "\n"
+	var events []TestEvent
+	handler := EventHandlerFunc(func(event TestEvent, _ *Execution) error {
+		events = append(events, event)
+		return nil
+	})
+	_, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(input),
+		Stderr:  strings.NewReader(""),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+	assert.Equal(t, len(events), len(lines))
+	for i, event := range events {
+		assert.Equal(t, string(event.Bytes()), lines[i])
+	}
+}
+
+func TestScanTestOutput_NonJSONOutput(t *testing.T) {
+	stdout := `{"Action":"run","Package":"example.com/pkg","Test":"TestOne"}
+go: warning: ignoring symlink /src/link
+
+{"Action":"pass","Package":"example.com/pkg","Test":"TestOne","Elapsed":0}
+{"Action":"pass","Package":"example.com/pkg","Elapsed":0.01}
+`
+	stderr := "go: downloading example.com/dep v1.0.0\n"
+	var events int
+	var errs []string
+	handler := &recordingHandler{
+		event: func(TestEvent) { events++ },
+		err:   func(text string) { errs = append(errs, text) },
+	}
+	exec, err := ScanTestOutput(ScanConfig{
+		Stdout:  strings.NewReader(stdout),
+		Stderr:  strings.NewReader(stderr),
+		Handler: handler,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, events, 3)
+	assert.Equal(t, exec.Total(), 1)
+	assert.Equal(t, len(exec.Failed()), 0)
+	// stdout and stderr are read concurrently, so the order is not known
+	sort.Strings(errs)
+	expected := []string{
+		"go: downloading example.com/dep v1.0.0",
+		"go: warning: ignoring symlink /src/link",
+	}
+	assert.DeepEqual(t, errs, expected)
+	assert.Equal(t, len(exec.Errors()), 2)
+}
+
+type recordingHandler struct {
+	event func(TestEvent)
+	err   func(string)
+}
+
+func (h *recordingHandler) Event(event TestEvent, _ *Execution) error {
+	h.event(event)
+	return nil
+}
+
+func (h *recordingHandler) Err(text string) error {
+	h.err(text)
+	return nil
+}
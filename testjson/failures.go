@@ -0,0 +1,113 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// FailureOutputLimit is the maximum number of bytes of test output printed by
+// PrintFailureOutput.
+type FailureOutputLimit struct {
+	// Total is the limit for the output of all the failed tests. The tests
+	// after the limit is reached are counted in a single line.
+	Total int
+	// PerTest is the limit for the output of each failed test. The end of the
+	// output is printed, because it usually has the failure message. A value
+	// of 0 prints all the output of each test, up to Total.
+	PerTest int
+}
+
+// PrintFailureOutput prints the complete output of each failed test case of
+// an Execution, so that all the failures can be read in one place after the
+// tests are done. The output is limited by limit.
+func PrintFailureOutput(out io.Writer, execution *Execution, limit FailureOutputLimit) error {
+	failed := execution.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, color.RedString("\n=== Failure output"))
+	var size int
+	for i, tc := range failed {
+		lines, omitted := tailLines(execution.Package(tc.Package).OutputLines(tc), limit.PerTest)
+		size += outputSize(lines)
+		if size > limit.Total {
+			fmt.Fprintf(out, "=== %s omitted, the limit of %d bytes was reached\n",
+				pluralizeFailures(len(failed)-i), limit.Total)
+			return nil
+		}
+		name := strings.TrimSpace(RelativePackagePath(tc.Package) + " " + tc.Test)
+		fmt.Fprintf(out, "=== %s: %s\n", color.RedString("FAIL"), name)
+		if omitted > 0 {
+			fmt.Fprintf(out, "... %d bytes of output omitted\n", omitted)
+		}
+		for _, line := range lines {
+			fmt.Fprint(out, line)
+		}
+	}
+	return nil
+}
+
+// tailLines returns the lines at the end of lines which fit in limit bytes,
+// and the number of bytes which were omitted. When the last line does not fit
+// the end of the line is returned.
+func tailLines(lines []string, limit int) ([]string, int) {
+	total := outputSize(lines)
+	if limit <= 0 || total <= limit {
+		return lines, 0
+	}
+	size := 0
+	i := len(lines)
+	for i > 0 && size+len(lines[i-1]) <= limit {
+		size += len(lines[i-1])
+		i--
+	}
+	if i == len(lines) {
+		last := lines[len(lines)-1]
+		return []string{last[len(last)-limit:]}, total - limit
+	}
+	return lines[i:], total - size
+}
+
+func pluralizeFailures(count int) string {
+	if count == 1 {
+		return "1 more failure"
+	}
+	return fmt.Sprintf("%d more failures", count)
+}
+
+func outputSize(lines []string) int {
+	var size int
+	for _, line := range lines {
+		size += len(line)
+	}
+	return size
+}
+
+// PrintFirstFailure prints the complete output of the failed test case which
+// failed first, using the time of the test events. In a run with many
+// failures the first failure is often the cause of the others. Nothing is
+// printed if no tests failed.
+func PrintFirstFailure(out io.Writer, execution *Execution) error {
+	failed := execution.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	first := failed[0]
+	for _, tc := range failed[1:] {
+		if !tc.time.IsZero() && (first.time.IsZero() || tc.time.Before(first.time)) {
+			first = tc
+		}
+	}
+	fmt.Fprintln(out, color.RedString("\n=== First failure"))
+	fmt.Fprintf(out, "=== %s: %s (%s)\n",
+		color.RedString("FAIL"),
+		strings.TrimSpace(RelativePackagePath(first.Package)+" "+first.Test),
+		FormatDurationAsSeconds(first.Elapsed, 2))
+	for _, line := range execution.Package(first.Package).OutputLines(first) {
+		fmt.Fprint(out, line)
+	}
+	return nil
+}
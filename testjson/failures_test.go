@@ -0,0 +1,115 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintFailureOutput(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestA"},
+					{Package: "example.com/one", Test: "TestB"},
+				},
+				output: map[string][]string{
+					"TestA": multiLine("=== RUN   TestA\n--- FAIL: TestA (0.00s)\n"),
+					"TestB": multiLine("=== RUN   TestB\n" + string(make([]byte, 100)) + "\n"),
+				},
+				action: ActionFail,
+			},
+			"example.com/two": {
+				action: ActionFail,
+				output: map[string][]string{"": multiLine("panic: boom\n")},
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintFailureOutput(out, exec, FailureOutputLimit{Total: 80}))
+
+	expected := `
+=== Failure output
+=== FAIL: one TestA
+=== RUN   TestA
+--- FAIL: TestA (0.00s)
+=== 2 more failures omitted, the limit of 80 bytes was reached
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintFailureOutputPerTestLimit(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Failed: []TestCase{{Package: "example.com/one", Test: "TestA"}},
+				output: map[string][]string{
+					"TestA": multiLine("=== RUN   TestA\nlots of output\none_test.go:9: boom\n"),
+				},
+				action: ActionFail,
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	limit := FailureOutputLimit{Total: 1000, PerTest: 20}
+	assert.NilError(t, PrintFailureOutput(out, exec, limit))
+
+	expected := `
+=== Failure output
+=== FAIL: one TestA
+... 31 bytes of output omitted
+one_test.go:9: boom
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestTailLines(t *testing.T) {
+	lines, omitted := tailLines([]string{"abc\n", "defghij\n"}, 4)
+	assert.DeepEqual(t, lines, []string{"hij\n"})
+	assert.Equal(t, omitted, 8)
+}
+
+func TestPrintFailureOutputNoFailures(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintFailureOutput(out, NewExecution(), FailureOutputLimit{Total: 80}))
+	assert.Equal(t, out.String(), "")
+}
+
+func TestPrintFirstFailure(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	exec := NewExecution()
+	for _, event := range []TestEvent{
+		{Action: ActionRun, Package: "example.com/a", Test: "TestLater"},
+		{Action: ActionRun, Package: "example.com/b", Test: "TestFirst"},
+		{Action: ActionOutput, Package: "example.com/b", Test: "TestFirst",
+			Output: "b_test.go:9: boom\n"},
+		{Action: ActionFail, Package: "example.com/b", Test: "TestFirst", Time: start, Elapsed: 0.5},
+		{Action: ActionFail, Package: "example.com/a", Test: "TestLater", Time: start.Add(time.Second)},
+	} {
+		exec.add(event)
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintFirstFailure(out, exec))
+
+	expected := `
+=== First failure
+=== FAIL: b TestFirst (0.50s)
+b_test.go:9: boom
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintFirstFailureNoFailures(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintFirstFailure(out, NewExecution()))
+	assert.Equal(t, out.String(), "")
+}
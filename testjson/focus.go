@@ -0,0 +1,36 @@
+package testjson
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FocusPackages returns an EventFormatter which sends the events of packages
+// which match focus to formatter. The events of other packages are hidden,
+// and a single line with the counts of the tests is returned when the package
+// is complete. The Execution still includes the events from every package.
+func FocusPackages(formatter EventFormatter, focus *regexp.Regexp) EventFormatter {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		switch {
+		case focus.MatchString(event.Package):
+			return formatter(event, exec)
+		case isPackageEnd(event):
+			return formatPackageCounts(event, exec.Package(event.Package)), nil
+		}
+		return "", nil
+	}
+}
+
+func formatPackageCounts(event TestEvent, pkg *Package) string {
+	label := strings.ToUpper(string(event.Action))
+	if event.Action == ActionSkip {
+		label = "EMPTY"
+	}
+	return fmt.Sprintf("%s %s (%d tests%s%s)\n",
+		colorEvent(event)(label),
+		RelativePackagePath(event.Package),
+		pkg.Total,
+		formatTestCount(len(pkg.Skipped), "skipped", ""),
+		formatTestCount(len(pkg.Failed), "failure", "s"))
+}
@@ -0,0 +1,50 @@
+package testjson
+
+import (
+	"regexp"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFocusPackages(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/other": {
+				Total:   3,
+				Failed:  []TestCase{{Package: "example.com/other", Test: "TestA"}},
+				Skipped: []TestCase{{Package: "example.com/other", Test: "TestB"}},
+			},
+		},
+	}
+	formatter := FocusPackages(standardVerboseFormat, regexp.MustCompile(`/focus$`))
+
+	var testcases = []struct {
+		event    TestEvent
+		expected string
+	}{
+		{
+			event:    TestEvent{Action: ActionOutput, Package: "example.com/focus", Output: "ok\n"},
+			expected: "ok\n",
+		},
+		{
+			event:    TestEvent{Action: ActionOutput, Package: "example.com/other", Output: "ok\n"},
+			expected: "",
+		},
+		{
+			event:    TestEvent{Action: ActionFail, Package: "example.com/other", Test: "TestA"},
+			expected: "",
+		},
+		{
+			event:    TestEvent{Action: ActionFail, Package: "example.com/other"},
+			expected: "FAIL other (3 tests, 1 skipped, 1 failure)\n",
+		},
+	}
+	for _, tc := range testcases {
+		actual, err := formatter(tc.event, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, actual, tc.expected)
+	}
+}
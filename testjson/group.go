@@ -0,0 +1,83 @@
+package testjson
+
+import (
+	"bytes"
+	"strings"
+)
+
+// GroupOutputByPackage returns an EventFormatter which buffers the output of
+// formatter for each package, and returns all the output for a package when
+// the package is complete. The output of each package is contiguous, even when
+// go test runs packages in parallel.
+//
+// When includeFailures is true the output of the failed tests of a package is
+// added after the output of the package. This is useful for formats which do
+// not print the test output.
+func GroupOutputByPackage(formatter EventFormatter, includeFailures bool) EventFormatter {
+	return groupOutput(formatter, includeFailures, false)
+}
+
+// CollapsePassingPackages returns an EventFormatter which buffers the output
+// of formatter for each package, like GroupOutputByPackage, and discards the
+// output of the packages which passed. Only the output of the packages which
+// failed, or had no tests, is returned.
+func CollapsePassingPackages(formatter EventFormatter, includeFailures bool) EventFormatter {
+	return groupOutput(formatter, includeFailures, true)
+}
+
+func groupOutput(formatter EventFormatter, includeFailures, dropPassed bool) EventFormatter {
+	buffers := make(map[string]*bytes.Buffer)
+	return func(event TestEvent, exec *Execution) (string, error) {
+		line, err := formatter(event, exec)
+		if err != nil {
+			return "", err
+		}
+		buf, ok := buffers[event.Package]
+		if !ok {
+			buf = new(bytes.Buffer)
+			buffers[event.Package] = buf
+		}
+		buf.WriteString(line)
+		if !isPackageEnd(event) {
+			return "", nil
+		}
+		delete(buffers, event.Package)
+		switch {
+		case dropPassed && event.Action == ActionPass:
+			return "", nil
+		case includeFailures && event.Action == ActionFail:
+			writePackageFailures(buf, exec, event.Package)
+		}
+		return buf.String(), nil
+	}
+}
+
+func isPackageEnd(event TestEvent) bool {
+	if !event.PackageEvent() {
+		return false
+	}
+	switch event.Action {
+	case ActionPass, ActionFail, ActionSkip:
+		return true
+	}
+	return false
+}
+
+// writePackageFailures writes the output of the tests which failed in the
+// current run of the package.
+func writePackageFailures(buf *bytes.Buffer, exec *Execution, name string) {
+	if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n") {
+		buf.WriteString("\n")
+	}
+	pkg := exec.Package(name)
+	conf := formatFailed()
+	if pkg.TestMainFailed() {
+		writeTestCase(buf, exec, conf, TestCase{Package: name, run: exec.runs})
+		return
+	}
+	for _, tc := range pkg.Failed {
+		if tc.run == exec.runs {
+			writeTestCase(buf, exec, conf, tc)
+		}
+	}
+}
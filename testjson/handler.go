@@ -0,0 +1,17 @@
+package testjson
+
+// EventHandlerFunc is an EventHandler implemented by a function. The function
+// is called with each event as it is read by ScanTestOutput, after the event
+// has been added to the Execution. Lines of stderr are not passed to the
+// function, but are still included in Execution.Errors.
+type EventHandlerFunc func(event TestEvent, execution *Execution) error
+
+// Event calls f with the event and the execution.
+func (f EventHandlerFunc) Event(event TestEvent, execution *Execution) error {
+	return f(event, execution)
+}
+
+// Err ignores the line of stderr.
+func (f EventHandlerFunc) Err(string) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package testjson
+
+// HidePassed returns an EventFormatter which hides the output of tests which
+// pass. The output of a test is buffered by the Execution until the test
+// completes. When a test fails or is skipped the buffered output is sent to
+// formatter, followed by the fail or skip event. Package events are always
+// sent to formatter.
+func HidePassed(formatter EventFormatter) EventFormatter {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		switch {
+		case event.PackageEvent():
+			return formatter(event, exec)
+		case event.Action == ActionFail, event.Action == ActionSkip:
+			return replayTestOutput(formatter, event, exec)
+		}
+		return "", nil
+	}
+}
+
+func replayTestOutput(formatter EventFormatter, event TestEvent, exec *Execution) (string, error) {
+	var result string
+	for _, line := range exec.OutputLines(event.Package, event.Test) {
+		out, err := formatter(TestEvent{
+			Time:    event.Time,
+			Action:  ActionOutput,
+			Package: event.Package,
+			Test:    event.Test,
+			Output:  line,
+		}, exec)
+		if err != nil {
+			return "", err
+		}
+		result += out
+	}
+	out, err := formatter(event, exec)
+	return result + out, err
+}
@@ -0,0 +1,106 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultHistogramBounds are the upper bounds of the buckets used by
+// TimingHistogram when no bounds are set.
+var DefaultHistogramBounds = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// HistogramBucket is a range of elapsed times, and the number of test cases
+// with an elapsed time in that range.
+type HistogramBucket struct {
+	// Min is the lower bound of the range, inclusive.
+	Min time.Duration
+	// Max is the upper bound of the range, exclusive. The last bucket has
+	// a Max of 0, because it has no upper bound.
+	Max   time.Duration
+	Count int
+}
+
+// TimingHistogram returns the number of test cases with an elapsed time in
+// each of the ranges defined by bounds. There is one more bucket than there
+// are bounds, for the test cases which take longer than the largest bound.
+// If bounds is empty DefaultHistogramBounds is used.
+func TimingHistogram(execution *Execution, bounds []time.Duration) []HistogramBucket {
+	if len(bounds) == 0 {
+		bounds = DefaultHistogramBounds
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	buckets := make([]HistogramBucket, len(bounds)+1)
+	for i, bound := range bounds {
+		buckets[i].Max = bound
+		buckets[i+1].Min = bound
+	}
+	for _, name := range execution.Packages() {
+		for _, tc := range execution.Package(name).TestCases() {
+			i := sort.Search(len(bounds), func(i int) bool { return tc.Elapsed < bounds[i] })
+			buckets[i].Count++
+		}
+	}
+	return buckets
+}
+
+// maxHistogramBarWidth is the width of the bar for the bucket with the most
+// test cases.
+const maxHistogramBarWidth = 40
+
+// PrintTimingHistogram prints a histogram of the elapsed time of the test
+// cases of an Execution, using the buckets from TimingHistogram.
+func PrintTimingHistogram(out io.Writer, execution *Execution, bounds []time.Duration) error {
+	buckets := TimingHistogram(execution, bounds)
+	var labels []string
+	var labelWidth, maxCount int
+	for _, bucket := range buckets {
+		label := bucketLabel(bucket)
+		labels = append(labels, label)
+		if len(label) > labelWidth {
+			labelWidth = len(label)
+		}
+		if bucket.Count > maxCount {
+			maxCount = bucket.Count
+		}
+	}
+	if maxCount == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out, "\n=== Timing histogram")
+	for i, bucket := range buckets {
+		line := fmt.Sprintf("%-*s %6d %s",
+			labelWidth, labels[i], bucket.Count, histogramBar(bucket.Count, maxCount))
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+func bucketLabel(bucket HistogramBucket) string {
+	if bucket.Max == 0 {
+		return ">= " + bucket.Min.String()
+	}
+	return "< " + bucket.Max.String()
+}
+
+// histogramBar returns a bar with a width relative to max. A bucket with at
+// least one test case always has a bar with a width of at least 1.
+func histogramBar(count int, max int) string {
+	if count == 0 || max == 0 {
+		return ""
+	}
+	width := count * maxHistogramBarWidth / max
+	if width == 0 {
+		width = 1
+	}
+	return strings.Repeat("#", width)
+}
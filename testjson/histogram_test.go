@@ -0,0 +1,58 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestTimingHistogram(t *testing.T) {
+	exec := newHistogramExecution()
+	bounds := []time.Duration{time.Second, 10 * time.Millisecond}
+	expected := []HistogramBucket{
+		{Max: 10 * time.Millisecond, Count: 2},
+		{Min: 10 * time.Millisecond, Max: time.Second, Count: 1},
+		{Min: time.Second, Count: 1},
+	}
+	assert.DeepEqual(t, TimingHistogram(exec, bounds), expected)
+}
+
+func TestPrintTimingHistogram(t *testing.T) {
+	out := new(bytes.Buffer)
+	err := PrintTimingHistogram(out, newHistogramExecution(), nil)
+	assert.NilError(t, err)
+
+	expected := `
+=== Timing histogram
+< 10ms       2 ########################################
+< 100ms      0
+< 1s         1 ####################
+>= 1s        1 ####################
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintTimingHistogramNoTests(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintTimingHistogram(out, NewExecution(), nil))
+	assert.Equal(t, out.String(), "")
+}
+
+func newHistogramExecution() *Execution {
+	return &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Passed: []TestCase{
+					{Test: "TestA"},
+					{Test: "TestB", Elapsed: 9 * time.Millisecond},
+					{Test: "TestC", Elapsed: 100 * time.Millisecond},
+				},
+			},
+			"example.com/two": {
+				Failed: []TestCase{{Test: "TestD", Elapsed: time.Second}},
+			},
+		},
+	}
+}
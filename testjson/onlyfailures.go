@@ -0,0 +1,16 @@
+package testjson
+
+import "strings"
+
+// OnlyFailures returns an EventFormatter which prints the complete output of
+// each failed test as soon as the test fails, and nothing for tests which pass
+// or are skipped. Unlike HidePassed the output is the same for every format.
+// When a package fails the output of the package is also printed.
+func OnlyFailures() EventFormatter {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if event.Action != ActionFail {
+			return "", nil
+		}
+		return strings.Join(exec.OutputLines(event.Package, event.Test), ""), nil
+	}
+}
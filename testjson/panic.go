@@ -0,0 +1,56 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// goroutineHeader matches the first line of the stack of a goroutine, which
+// the runtime prints after the panic message. Ex: goroutine 8 [running]:
+var goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[[^\]]+\]:$`)
+
+// IsPanicOutput returns true if the lines of output include a panic. A panic
+// is a line which starts with "panic: ", followed by the stack of a goroutine.
+// A test which logs or prints the word panic is not reported as a panic,
+// because the runtime does not print a stack for it. The panic of a -timeout
+// is reported as a timeout, not a panic.
+func IsPanicOutput(lines []string) bool {
+	var panicked bool
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "panic: ") && !isTimeoutLine(line):
+			panicked = true
+		case panicked && goroutineHeader.MatchString(strings.TrimSuffix(line, "\n")):
+			return true
+		}
+	}
+	return false
+}
+
+// Panicked returns the failed test cases with output which includes a panic.
+func (e *Execution) Panicked() []TestCase {
+	var panicked []TestCase
+	for _, tc := range e.Failed() {
+		if IsPanicOutput(e.Package(tc.Package).OutputLines(tc)) {
+			panicked = append(panicked, tc)
+		}
+	}
+	return panicked
+}
+
+// writePanicSummary prints the name of each failed test which panicked.
+// Nothing is printed if no tests panicked.
+func writePanicSummary(out io.Writer, execution *Execution) {
+	panicked := execution.Panicked()
+	if len(panicked) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.RedString("\n=== Panic in:"))
+	for _, tc := range panicked {
+		fmt.Fprintln(out, strings.TrimSpace(RelativePackagePath(tc.Package)+" "+tc.Test))
+	}
+}
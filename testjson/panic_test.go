@@ -0,0 +1,79 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestIsPanicOutput(t *testing.T) {
+	var testcases = []struct {
+		name     string
+		lines    []string
+		expected bool
+	}{
+		{
+			name: "panic with stack",
+			lines: []string{
+				"=== RUN   TestPanics\n",
+				"panic: this is a panic [recovered]\n",
+				"\tpanic: this is a panic\n",
+				"\n",
+				"goroutine 8 [running]:\n",
+			},
+			expected: true,
+		},
+		{
+			name:  "logged panic",
+			lines: []string{"    foo_test.go:12: panic: not really\n", "goroutine 8 [running]:\n"},
+		},
+		{
+			name:  "printed panic without a stack",
+			lines: []string{"panic: is just a word here\n", "--- FAIL: TestPanics (0.00s)\n"},
+		},
+		{
+			name:  "stack without a panic",
+			lines: []string{"goroutine 1 [chan receive]:\n"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, IsPanicOutput(tc.lines), tc.expected)
+		})
+	}
+}
+
+func TestPrintSummaryWithPanics(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := &Execution{started: fake.Now(), packages: map[string]*Package{}}
+	for _, event := range []TestEvent{
+		{Action: ActionRun, Package: "example.com/a", Test: "TestPanics"},
+		{Action: ActionOutput, Package: "example.com/a", Test: "TestPanics",
+			Output: "panic: boom\n"},
+		{Action: ActionOutput, Package: "example.com/a", Test: "TestPanics", Output: "\n"},
+		{Action: ActionOutput, Package: "example.com/a", Test: "TestPanics",
+			Output: "goroutine 7 [running]:\n"},
+		{Action: ActionFail, Package: "example.com/a", Test: "TestPanics"},
+		{Action: ActionRun, Package: "example.com/a", Test: "TestFails"},
+		{Action: ActionOutput, Package: "example.com/a", Test: "TestFails",
+			Output: "panic: is only a word\n"},
+		{Action: ActionFail, Package: "example.com/a", Test: "TestFails"},
+		{Action: ActionFail, Package: "example.com/a"},
+	} {
+		exec.add(event)
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSummary(out, exec, SummarizePanics))
+
+	expected := `
+=== Panic in:
+a TestPanics
+
+DONE 2 tests, 2 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
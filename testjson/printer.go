@@ -5,7 +5,10 @@ import (
 	"go/build"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
 )
@@ -24,6 +27,11 @@ func debugFormat(event TestEvent, _ *Execution) (string, error) {
 		event.Output), nil
 }
 
+// go test -json
+func standardJSONFormat(event TestEvent, _ *Execution) (string, error) {
+	return string(event.Bytes()) + "\n", nil
+}
+
 // go test -v
 func standardVerboseFormat(event TestEvent, _ *Execution) (string, error) {
 	if event.Action == ActionOutput {
@@ -40,37 +48,84 @@ func standardQuietFormat(event TestEvent, _ *Execution) (string, error) {
 	return "", nil
 }
 
-func shortVerboseFormat(event TestEvent, exec *Execution) (string, error) {
-	result := colorEvent(event)(strings.ToUpper(string(event.Action)))
-	formatTest := func() string {
-		return fmt.Sprintf("%s %s.%s %s\n",
-			result,
-			relativePackagePath(event.Package),
-			event.Test,
-			event.ElapsedFormatted())
+// newStandardVerboseFormat returns the standard-verbose format. When
+// opts.SlowTestThreshold is set, the elapsed time on the result line of a test
+// which took longer than the threshold is highlighted.
+func newStandardVerboseFormat(opts FormatOptions) EventFormatter {
+	if opts.SlowTestThreshold <= 0 {
+		return standardVerboseFormat
+	}
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if event.Action != ActionOutput {
+			return "", nil
+		}
+		return opts.highlightSlowResult(event.Output), nil
 	}
+}
 
-	switch {
-	case isPkgFailureOutput(event):
-		return event.Output, nil
+// testResultLine matches the line printed by go test at the end of a test, and
+// captures the elapsed time. Ex: --- PASS: TestOne (0.03s)
+var testResultLine = regexp.MustCompile(`^(\s*--- [A-Z]+: .+ \()(\d+\.\d+s)(\)\n)$`)
 
-	case event.PackageEvent():
-		switch event.Action {
-		case ActionSkip:
-			result = colorEvent(event)("EMPTY")
-			fallthrough
-		case ActionPass, ActionFail:
-			return fmt.Sprintf("%s %s\n", result, relativePackagePath(event.Package)), nil
+func (o FormatOptions) highlightSlowResult(line string) string {
+	match := testResultLine.FindStringSubmatch(line)
+	if match == nil {
+		return line
+	}
+	elapsed, err := time.ParseDuration(match[2])
+	if err != nil {
+		return line
+	}
+	return match[1] + o.colorElapsed(elapsed, match[2]) + match[3]
+}
+
+// colorElapsed returns the formatted elapsed time of a test, highlighted when
+// the elapsed time is greater than SlowTestThreshold.
+func (o FormatOptions) colorElapsed(elapsed time.Duration, formatted string) string {
+	if o.SlowTestThreshold <= 0 || elapsed < o.SlowTestThreshold {
+		return formatted
+	}
+	return color.YellowString(formatted)
+}
+
+func newShortVerboseFormat(opts FormatOptions) EventFormatter {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		label := strings.ToUpper(string(event.Action))
+		formatTest := func() string {
+			elapsed := event.ElapsedFormatted()
+			name := RelativePackagePath(event.Package) + "." + event.Test
+			return fmt.Sprintf("%s %s %s\n",
+				colorEvent(event)(label),
+				opts.fit(name, len(label)+len(elapsed)+2),
+				opts.colorElapsed(elapsedDuration(event.Elapsed), elapsed))
+		}
+		formatPkg := func() string {
+			name := opts.fit(RelativePackagePath(event.Package), len(label)+1)
+			return fmt.Sprintf("%s %s\n", colorEvent(event)(label), name)
 		}
 
-	case event.Action == ActionFail:
-		return exec.Output(event.Package, event.Test) + formatTest(), nil
+		switch {
+		case isPkgFailureOutput(event):
+			return event.Output, nil
 
-	case event.Action == ActionPass:
-		return formatTest(), nil
+		case event.PackageEvent():
+			switch event.Action {
+			case ActionSkip:
+				label = "EMPTY"
+				fallthrough
+			case ActionPass, ActionFail:
+				return formatPkg(), nil
+			}
 
+		case event.Action == ActionFail:
+			return exec.Output(event.Package, event.Test) + formatTest(), nil
+
+		case event.Action == ActionPass:
+			return formatTest(), nil
+
+		}
+		return "", nil
 	}
-	return "", nil
 }
 
 // isPkgFailureOutput returns true if the event is package output, and the output
@@ -99,50 +154,120 @@ func all(cond ...bool) bool {
 	return true
 }
 
-func shortFormat(event TestEvent, _ *Execution) (string, error) {
-	if !event.PackageEvent() {
+func newShortFormat(opts FormatOptions) EventFormatter {
+	return func(event TestEvent, _ *Execution) (string, error) {
+		if !event.PackageEvent() {
+			return "", nil
+		}
+		fmtElapsed := func() string {
+			d := elapsedDuration(event.Elapsed)
+			if d == 0 {
+				return ""
+			}
+			return fmt.Sprintf(" (%s)", d)
+		}
+		fmtEvent := func(action string) (string, error) {
+			elapsed := fmtElapsed()
+			// the action is a single character followed by 2 spaces
+			name := opts.fit(RelativePackagePath(event.Package), utf8.RuneCountInString(elapsed)+3)
+			return fmt.Sprintf("%s  %s%s\n", action, name, elapsed), nil
+		}
+		withColor := colorEvent(event)
+		switch event.Action {
+		case ActionSkip:
+			return fmtEvent(withColor("∅"))
+		case ActionPass:
+			return fmtEvent(withColor("✓"))
+		case ActionFail:
+			return fmtEvent(withColor("✖"))
+		}
 		return "", nil
 	}
-	fmtElapsed := func() string {
-		d := elapsedDuration(event.Elapsed)
-		if d == 0 {
-			return ""
+}
+
+// newShortWithFailuresFormat returns a formatter which prints the line of the
+// short format for each package, followed by the output of the tests which
+// failed in the package, so that failures can be read as soon as the package
+// is complete.
+func newShortWithFailuresFormat(opts FormatOptions) EventFormatter {
+	short := newShortFormat(opts)
+	conf := formatFailed()
+	return func(event TestEvent, exec *Execution) (string, error) {
+		line, err := short(event, exec)
+		if err != nil || !event.PackageEvent() || event.Action != ActionFail {
+			return line, err
 		}
-		return fmt.Sprintf(" (%s)", d)
-	}
-	fmtEvent := func(action string) (string, error) {
-		return fmt.Sprintf("%s  %s%s\n",
-			action, relativePackagePath(event.Package), fmtElapsed()), nil
+		buf := new(strings.Builder)
+		buf.WriteString(line)
+		for _, tc := range exec.Package(event.Package).Failed {
+			if tc.run == exec.runs {
+				writeTestCase(buf, exec, conf, tc)
+			}
+		}
+		return buf.String(), nil
 	}
-	withColor := colorEvent(event)
-	switch event.Action {
-	case ActionSkip:
-		return fmtEvent(withColor("∅"))
-	case ActionPass:
-		return fmtEvent(withColor("✓"))
-	case ActionFail:
-		return fmtEvent(withColor("✖"))
+}
+
+// newPkgnameFormat returns a formatter which prints a line for each package,
+// with the counts of the tests in the package. Failed packages are printed in
+// red.
+func newPkgnameFormat(opts FormatOptions) EventFormatter {
+	return func(event TestEvent, exec *Execution) (string, error) {
+		if !isPackageEnd(event) {
+			return "", nil
+		}
+		pkg := exec.Package(event.Package)
+		label := strings.ToUpper(string(event.Action))
+		counts := fmt.Sprintf("%d passed, %d failed, %d skipped",
+			len(pkg.Passed), len(pkg.Failed), len(pkg.Skipped))
+		if event.Action == ActionSkip {
+			label, counts = "EMPTY", "no tests"
+		}
+		if d := elapsedDuration(event.Elapsed); d != 0 {
+			counts += fmt.Sprintf(", %s", d)
+		}
+		// the label is padded to 5 characters followed by a space
+		name := opts.fit(RelativePackagePath(event.Package), utf8.RuneCountInString(counts)+9)
+		line := fmt.Sprintf("%-5s %s (%s)", label, name, counts)
+		if event.Action == ActionFail {
+			line = color.RedString("%s", line)
+		}
+		return line + "\n", nil
 	}
-	return "", nil
 }
 
-func dotsFormat(event TestEvent, exec *Execution) (string, error) {
-	pkg := exec.Package(event.Package)
-	withColor := colorEvent(event)
+func newDotsFormat(opts FormatOptions) EventFormatter {
+	pass := withDefault(opts.DotsPass, "·")
+	fail := withDefault(opts.DotsFail, "✖")
+	skip := withDefault(opts.DotsSkip, "↷")
+	return func(event TestEvent, exec *Execution) (string, error) {
+		pkg := exec.Package(event.Package)
+		withColor := colorEvent(event)
+		if opts.DotsNoColor {
+			withColor = fmt.Sprintf
+		}
 
-	switch {
-	case event.PackageEvent():
+		switch {
+		case event.PackageEvent():
+			return "", nil
+		case event.Action == ActionRun && pkg.Total == 1:
+			return "[" + RelativePackagePath(event.Package) + "]", nil
+		case event.Action == ActionPass:
+			return withColor("%s", pass), nil
+		case event.Action == ActionFail:
+			return withColor("%s", fail), nil
+		case event.Action == ActionSkip:
+			return withColor("%s", skip), nil
+		}
 		return "", nil
-	case event.Action == ActionRun && pkg.Total == 1:
-		return "[" + relativePackagePath(event.Package) + "]", nil
-	case event.Action == ActionPass:
-		return withColor("·"), nil
-	case event.Action == ActionFail:
-		return withColor("✖"), nil
-	case event.Action == ActionSkip:
-		return withColor("↷"), nil
 	}
-	return "", nil
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
 }
 
 func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
@@ -157,7 +282,10 @@ func colorEvent(event TestEvent) func(format string, a ...interface{}) string {
 	return color.WhiteString
 }
 
-func relativePackagePath(pkgpath string) string {
+// RelativePackagePath returns a package path relative to the package path of
+// the current working directory, or the full package path if the package is
+// not relative to the working directory.
+func RelativePackagePath(pkgpath string) string {
 	if pkgpath == pkgPathPrefix {
 		return "."
 	}
@@ -179,22 +307,96 @@ func getPkgPathPrefix() string {
 
 var pkgPathPrefix = getPkgPathPrefix()
 
+// SetPackagePathPrefix sets the package path which is removed from the start
+// of a package path by RelativePackagePath. By default the prefix is the
+// package path of the working directory in the GOPATH. An empty prefix
+// disables the removal, so that full package paths are printed.
+func SetPackagePathPrefix(prefix string) {
+	pkgPathPrefix = prefix
+}
+
+// PackagePathPrefix returns the package path which is removed from the start
+// of a package path by RelativePackagePath.
+func PackagePathPrefix() string {
+	return pkgPathPrefix
+}
+
+// FormatOptions configures the output of an EventFormatter.
+type FormatOptions struct {
+	// MaxLineWidth is the maximum width of the lines printed by the short and
+	// short-verbose formats. Package and test names are truncated to fit the
+	// width. A value of 0 disables truncation.
+	MaxLineWidth int
+	// DotsPass, DotsFail, and DotsSkip replace the characters printed by the
+	// dots format for a test which passed, failed, or was skipped. An empty
+	// value uses the default character.
+	DotsPass string
+	DotsFail string
+	DotsSkip string
+	// DotsNoColor disables the color of the characters printed by the dots
+	// format.
+	DotsNoColor bool
+	// SlowTestThreshold highlights the elapsed time of the tests which took
+	// longer than the threshold, in the standard-verbose and short-verbose
+	// formats. A value of 0 disables the highlight.
+	SlowTestThreshold time.Duration
+}
+
+// fit truncates name so that it fits in the line width, when the rest of the
+// line uses width columns. The start of the name is removed, because the end
+// of a package path or test name is the most specific part.
+func (o FormatOptions) fit(name string, width int) string {
+	if o.MaxLineWidth <= 0 {
+		return name
+	}
+	return truncateStart(name, o.MaxLineWidth-width)
+}
+
+func truncateStart(value string, width int) string {
+	runes := []rune(value)
+	switch {
+	case len(runes) <= width:
+		return value
+	case width <= 1:
+		return "…"
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
+
 // NewEventFormatter returns a formatter for printing events.
 func NewEventFormatter(format string) EventFormatter {
-	switch format {
-	case "debug":
-		return debugFormat
-	case "standard-verbose":
-		return standardVerboseFormat
-	case "standard-quiet":
-		return standardQuietFormat
-	case "dots":
-		return dotsFormat
-	case "short-verbose":
-		return shortVerboseFormat
-	case "short":
-		return shortFormat
-	default:
+	return NewEventFormatterWithOptions(format, FormatOptions{})
+}
+
+// NewEventFormatterWithOptions returns a formatter for printing events,
+// configured by opts.
+func NewEventFormatterWithOptions(format string, opts FormatOptions) EventFormatter {
+	newFormat, ok := formats[format]
+	if !ok {
 		return nil
 	}
+	return newFormat(opts)
+}
+
+// formats maps the name of each format to the function which creates the
+// formatter.
+var formats = map[string]func(opts FormatOptions) EventFormatter{
+	"debug":               staticFormat(debugFormat),
+	"standard-verbose":    newStandardVerboseFormat,
+	"standard-quiet":      staticFormat(standardQuietFormat),
+	"standard-json":       staticFormat(standardJSONFormat),
+	"dots":                newDotsFormat,
+	"short-verbose":       newShortVerboseFormat,
+	"short":               newShortFormat,
+	"short-with-failures": newShortWithFailuresFormat,
+	"pkgname":             newPkgnameFormat,
+	"tap":                 staticFormat(tapFormat),
+}
+
+// staticFormat returns a function which creates formatter, for formats which
+// have no options.
+func staticFormat(formatter EventFormatter) func(FormatOptions) EventFormatter {
+	return func(FormatOptions) EventFormatter {
+		return formatter
+	}
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fatih/color"
 	gocmp "github.com/google/go-cmp/cmp"
 	"gotest.tools/assert"
 	"gotest.tools/assert/opt"
@@ -55,11 +56,11 @@ func patchPkgPathPrefix(val string) func() {
 }
 
 func TestRelativePackagePath(t *testing.T) {
-	relPath := relativePackagePath(
+	relPath := RelativePackagePath(
 		"gotest.tools/gotestsum/testjson/extra/relpath")
 	assert.Equal(t, relPath, "extra/relpath")
 
-	relPath = relativePackagePath(
+	relPath = RelativePackagePath(
 		"gotest.tools/gotestsum/testjson")
 	assert.Equal(t, relPath, ".")
 }
@@ -71,7 +72,7 @@ func TestGetPkgPathPrefix(t *testing.T) {
 func TestScanTestOutputWithShortVerboseFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandler(shortVerboseFormat, "go-test-json")
+	shim := newFakeHandler(NewEventFormatter("short-verbose"), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -81,8 +82,13 @@ func TestScanTestOutputWithShortVerboseFormat(t *testing.T) {
 }
 
 var expectedExecution = &Execution{
-	started: time.Now(),
-	errors:  []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	started:  time.Now(),
+	finished: time.Now(),
+	runs:     1,
+	errors:   []string{"internal/broken/broken.go:5:21: undefined: somepackage"},
+	buildFailures: []string{
+		"github.com/gotestyourself/gotestyourself/testjson/internal/broken",
+	},
 	packages: map[string]*Package{
 		"github.com/gotestyourself/gotestyourself/testjson/internal/good": {
 			Total: 18,
@@ -90,7 +96,8 @@ var expectedExecution = &Execution{
 				{Test: "TestSkipped"},
 				{Test: "TestSkippedWitLog"},
 			},
-			action: ActionPass,
+			action:  ActionPass,
+			started: time.Date(2018, 3, 22, 22, 33, 35, 167978423, time.UTC),
 		},
 		"github.com/gotestyourself/gotestyourself/testjson/internal/stub": {
 			Total: 28,
@@ -104,10 +111,12 @@ var expectedExecution = &Execution{
 				{Test: "TestSkipped"},
 				{Test: "TestSkippedWitLog"},
 			},
-			action: ActionFail,
+			action:  ActionFail,
+			started: time.Date(2018, 3, 22, 22, 33, 35, 277691480, time.UTC),
 		},
 		"github.com/gotestyourself/gotestyourself/testjson/internal/badmain": {
-			action: ActionFail,
+			action:  ActionFail,
+			started: time.Date(2018, 3, 22, 22, 33, 35, 147671743, time.UTC),
 		},
 	},
 }
@@ -115,6 +124,7 @@ var expectedExecution = &Execution{
 var cmpExecutionShallow = gocmp.Options{
 	gocmp.AllowUnexported(Execution{}, Package{}),
 	gocmp.FilterPath(stringPath("started"), opt.TimeWithThreshold(10*time.Second)),
+	gocmp.FilterPath(stringPath("finished"), opt.TimeWithThreshold(10*time.Second)),
 	cmpPackageShallow,
 }
 
@@ -136,7 +146,7 @@ func stringPath(spec string) func(gocmp.Path) bool {
 func TestScanTestOutputWithDotsFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandler(dotsFormat, "go-test-json")
+	shim := newFakeHandler(NewEventFormatter("dots"), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -148,7 +158,7 @@ func TestScanTestOutputWithDotsFormat(t *testing.T) {
 func TestScanTestOutputWithShortFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
-	shim := newFakeHandler(shortFormat, "go-test-json")
+	shim := newFakeHandler(NewEventFormatter("short"), "go-test-json")
 	exec, err := ScanTestOutput(shim.Config(t))
 
 	assert.NilError(t, err)
@@ -157,6 +167,30 @@ func TestScanTestOutputWithShortFormat(t *testing.T) {
 	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
 }
 
+func TestScanTestOutputWithShortWithFailuresFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(NewEventFormatter("short-with-failures"), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "short-with-failures-format.out")
+	golden.Assert(t, shim.err.String(), "short-format.err")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithPkgnameFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(NewEventFormatter("pkgname"), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "pkgname-format.out")
+	golden.Assert(t, shim.err.String(), "short-format.err")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
 func TestScanTestOutputWithStandardVerboseFormat(t *testing.T) {
 	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
 
@@ -180,3 +214,181 @@ func TestScanTestOutputWithStandardQuietFormat(t *testing.T) {
 	golden.Assert(t, shim.err.String(), "standard-quiet-format.err")
 	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
 }
+
+func TestScanTestOutputWithStandardJSONFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(standardJSONFormat, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "standard-json-format.out")
+	golden.Assert(t, shim.err.String(), "standard-json-format.err")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithShortFormatGroupedByPackage(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	formatter := GroupOutputByPackage(NewEventFormatter("short"), true)
+	shim := newFakeHandler(formatter, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "short-format-grouped.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithDotsFormatGroupedByPackage(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	formatter := GroupOutputByPackage(NewEventFormatter("dots"), false)
+	shim := newFakeHandler(formatter, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "dots-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithShortFormatCollapsePassingPackages(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	formatter := CollapsePassingPackages(NewEventFormatter("short"), true)
+	shim := newFakeHandler(formatter, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "short-format-collapsed.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithStandardVerboseFormatHidePassed(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(HidePassed(standardVerboseFormat), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "go-test-verbose-hide-passed.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithShortVerboseFormatHidePassed(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(HidePassed(NewEventFormatter("short-verbose")), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "short-verbose-format-hide-passed.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithOnlyFailures(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(OnlyFailures(), "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	golden.Assert(t, shim.out.String(), "only-failures.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestScanTestOutputWithTAPFormat(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+
+	shim := newFakeHandler(tapFormat, "go-test-json")
+	exec, err := ScanTestOutput(shim.Config(t))
+
+	assert.NilError(t, err)
+	shim.out.WriteString(TAPPlan(exec))
+	golden.Assert(t, shim.out.String(), "tap-format.out")
+	assert.DeepEqual(t, exec, expectedExecution, cmpExecutionShallow)
+}
+
+func TestShortFormatsWithMaxLineWidth(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	opts := FormatOptions{MaxLineWidth: 24}
+	pkgEvent := TestEvent{
+		Action:  ActionPass,
+		Package: "example.com/project/internal/store",
+		Elapsed: 0.5,
+	}
+	out, err := NewEventFormatterWithOptions("short", opts)(pkgEvent, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "✓  …ternal/store (500ms)\n")
+
+	testEvent := TestEvent{
+		Action:  ActionPass,
+		Package: "example.com/project/store",
+		Test:    "TestSaveAll",
+	}
+	out, err = NewEventFormatterWithOptions("short-verbose", opts)(testEvent, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "PASS …estSaveAll (0.00s)\n")
+
+	out, err = NewEventFormatterWithOptions("short-verbose", FormatOptions{})(testEvent, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "PASS project/store.TestSaveAll (0.00s)\n")
+}
+
+func TestDotsFormatWithOptions(t *testing.T) {
+	formatter := NewEventFormatterWithOptions("dots", FormatOptions{
+		DotsPass:    ".",
+		DotsFail:    "F",
+		DotsSkip:    "%",
+		DotsNoColor: true,
+	})
+	exec := NewExecution()
+	var testcases = []struct {
+		action   Action
+		expected string
+	}{
+		{action: ActionPass, expected: "."},
+		{action: ActionFail, expected: "F"},
+		{action: ActionSkip, expected: "%"},
+	}
+	for _, tc := range testcases {
+		event := TestEvent{Action: tc.action, Package: "example.com/pkg", Test: "TestOne"}
+		exec.add(event)
+		actual, err := formatter(event, exec)
+		assert.NilError(t, err)
+		assert.Equal(t, actual, tc.expected)
+	}
+}
+
+func TestTruncateStart(t *testing.T) {
+	assert.Equal(t, truncateStart("short", 10), "short")
+	assert.Equal(t, truncateStart("a/long/path", 6), "…/path")
+	assert.Equal(t, truncateStart("a/long/path", 0), "…")
+}
+
+func TestFormatWithSlowTestThreshold(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	defer func(orig bool) { color.NoColor = orig }(color.NoColor)
+	color.NoColor = false
+	opts := FormatOptions{SlowTestThreshold: time.Second}
+	slow := color.YellowString("1.20s")
+
+	formatter := NewEventFormatterWithOptions("standard-verbose", opts)
+	out, err := formatter(TestEvent{Action: ActionOutput, Output: "--- PASS: TestOne (1.20s)\n"}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "--- PASS: TestOne ("+slow+")\n")
+
+	fast := "    --- FAIL: TestOne/a (0.10s)\n"
+	out, err = formatter(TestEvent{Action: ActionOutput, Output: fast}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, out, fast)
+
+	formatter = NewEventFormatterWithOptions("short-verbose", opts)
+	event := TestEvent{
+		Action: ActionPass, Package: "example.com/pkg", Test: "TestOne", Elapsed: 1.2,
+	}
+	out, err = formatter(event, nil)
+	assert.NilError(t, err)
+	expected := color.GreenString("PASS") + " pkg.TestOne " + color.YellowString("(1.20s)") + "\n"
+	assert.Equal(t, out, expected)
+}
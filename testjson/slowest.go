@@ -0,0 +1,84 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// SlowestTestCases returns the num test cases with the longest elapsed time,
+// sorted by elapsed time in descending order. Test cases with the same
+// elapsed time are sorted by name. Test cases with no recorded elapsed time
+// are ignored.
+func SlowestTestCases(execution *Execution, num int) []TestCase {
+	var testCases []TestCase
+	for _, name := range execution.Packages() {
+		for _, tc := range execution.Package(name).TestCases() {
+			if tc.Elapsed > 0 {
+				testCases = append(testCases, tc)
+			}
+		}
+	}
+	sort.SliceStable(testCases, func(i, j int) bool {
+		a, b := testCases[i], testCases[j]
+		switch {
+		case a.Elapsed != b.Elapsed:
+			return a.Elapsed > b.Elapsed
+		case a.Test != b.Test:
+			return a.Test < b.Test
+		}
+		return a.Package < b.Package
+	})
+	if len(testCases) > num {
+		testCases = testCases[:num]
+	}
+	return testCases
+}
+
+// PrintSlowest prints the num slowest test cases of an Execution.
+func PrintSlowest(out io.Writer, execution *Execution, num int) error {
+	testCases := SlowestTestCases(execution, num)
+	if len(testCases) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "\n=== Slowest")
+	for _, tc := range testCases {
+		fmt.Fprintf(out, "%s %s (%s)\n",
+			RelativePackagePath(tc.Package),
+			tc.Test,
+			FormatDurationAsSeconds(tc.Elapsed, 2))
+	}
+	return nil
+}
+
+// SlowPackages returns the names of the packages with a total elapsed time
+// greater than threshold, sorted by elapsed time in descending order.
+func SlowPackages(execution *Execution, threshold time.Duration) []string {
+	var names []string
+	for _, name := range execution.Packages() {
+		if execution.Package(name).Elapsed() > threshold {
+			names = append(names, name)
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return execution.Package(names[i]).Elapsed() > execution.Package(names[j]).Elapsed()
+	})
+	return names
+}
+
+// PrintSlowPackages prints the packages of an Execution with a total elapsed
+// time greater than threshold.
+func PrintSlowPackages(out io.Writer, execution *Execution, threshold time.Duration) error {
+	names := SlowPackages(execution, threshold)
+	if len(names) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "\n=== Slow packages (over %s)\n", threshold)
+	for _, name := range names {
+		fmt.Fprintf(out, "%s (%s)\n",
+			RelativePackagePath(name),
+			FormatDurationAsSeconds(execution.Package(name).Elapsed(), 2))
+	}
+	return nil
+}
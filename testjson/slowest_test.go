@@ -0,0 +1,109 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	gocmp "github.com/google/go-cmp/cmp"
+	"gotest.tools/assert"
+)
+
+func TestSlowestTestCases(t *testing.T) {
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Passed: []TestCase{
+					{Package: "example.com/one", Test: "TestB", Elapsed: 2 * time.Second},
+					{Package: "example.com/one", Test: "TestZero"},
+					{Package: "example.com/one", Test: "TestA", Elapsed: 2 * time.Second},
+				},
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestFail", Elapsed: time.Second},
+				},
+			},
+			"example.com/two": {
+				Passed: []TestCase{
+					{Package: "example.com/two", Test: "TestC", Elapsed: 3 * time.Second},
+					{Package: "example.com/two", Test: "TestQuick", Elapsed: time.Millisecond},
+				},
+			},
+		},
+	}
+
+	actual := SlowestTestCases(exec, 4)
+	expected := []TestCase{
+		{Package: "example.com/two", Test: "TestC", Elapsed: 3 * time.Second},
+		{Package: "example.com/one", Test: "TestA", Elapsed: 2 * time.Second},
+		{Package: "example.com/one", Test: "TestB", Elapsed: 2 * time.Second},
+		{Package: "example.com/one", Test: "TestFail", Elapsed: time.Second},
+	}
+	assert.DeepEqual(t, actual, expected, gocmp.AllowUnexported(TestCase{}))
+
+	actual = SlowestTestCases(exec, 10)
+	assert.Equal(t, len(actual), 5)
+}
+
+func TestPrintSlowest(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Passed: []TestCase{
+					{Package: "example.com/one", Test: "TestA", Elapsed: 20 * time.Millisecond},
+					{Package: "example.com/one", Test: "TestB", Elapsed: 1250 * time.Millisecond},
+				},
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSlowest(out, exec, 5))
+
+	expected := `
+=== Slowest
+one TestB (1.25s)
+one TestA (0.02s)
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSlowestNoTests(t *testing.T) {
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSlowest(out, NewExecution(), 5))
+	assert.Equal(t, out.String(), "")
+}
+
+func TestPrintSlowPackages(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Passed: []TestCase{
+					{Package: "example.com/one", Test: "TestA", Elapsed: 20 * time.Second},
+					{Package: "example.com/one", Test: "TestB", Elapsed: 15 * time.Second},
+				},
+			},
+			"example.com/two": {
+				Passed: []TestCase{
+					{Package: "example.com/two", Test: "TestC", Elapsed: 10 * time.Second},
+				},
+			},
+			"example.com/three": {
+				Failed: []TestCase{
+					{Package: "example.com/three", Test: "TestD", Elapsed: 40 * time.Second},
+				},
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSlowPackages(out, exec, 30*time.Second))
+
+	expected := `
+=== Slow packages (over 30s)
+three (40.00s)
+one (35.00s)
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -0,0 +1,112 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// rootTestName returns the name of the top level test of a subtest, or name
+// if it is not a subtest.
+func rootTestName(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// subtestGroup is a failed top level test and its failed subtests.
+type subtestGroup struct {
+	root     TestCase
+	hasRoot  bool
+	subtests []TestCase
+}
+
+// groupSubtests groups the test cases by package, top level test, and run, in
+// the order in which the first test case of each group is found.
+func groupSubtests(testCases []TestCase) []*subtestGroup {
+	var groups []*subtestGroup
+	index := make(map[string]*subtestGroup)
+	for _, tc := range testCases {
+		root := TestCase{Package: tc.Package, Test: rootTestName(tc.Test), run: tc.run}
+		key := root.Package + " " + outputKey(root.Test, root.run)
+		group, ok := index[key]
+		if !ok {
+			group = &subtestGroup{root: root}
+			index[key] = group
+			groups = append(groups, group)
+		}
+		if tc.Test == group.root.Test {
+			group.root, group.hasRoot = tc, true
+			continue
+		}
+		group.subtests = append(group.subtests, tc)
+	}
+	return groups
+}
+
+// countSubtests returns the number of direct subtests of root in failed, and
+// in all the test cases of the package.
+func countSubtests(pkg *Package, root string, failed []TestCase) (int, int) {
+	isChild := func(name string) bool {
+		return strings.HasPrefix(name, root+"/") && !strings.Contains(name[len(root)+1:], "/")
+	}
+	count := func(groups ...[]TestCase) int {
+		names := make(map[string]bool)
+		for _, testCases := range groups {
+			for _, tc := range testCases {
+				if isChild(tc.Test) {
+					names[tc.Test] = true
+				}
+			}
+		}
+		return len(names)
+	}
+	return count(failed), count(pkg.Passed, pkg.Failed, pkg.Skipped)
+}
+
+// writeSubtestGroups prints each top level test with failed subtests as a
+// single entry, with the number of direct subtests which failed, followed by
+// the failed subtests. Other test cases are printed the same as without the
+// groups.
+func writeSubtestGroups(
+	out io.Writer,
+	execution *Execution,
+	conf testCaseFormatConfig,
+	testCases []TestCase,
+) {
+	for _, group := range groupSubtests(testCases) {
+		if len(group.subtests) == 0 {
+			writeTestCase(out, execution, conf, group.root)
+			continue
+		}
+		pkg := execution.Package(group.root.Package)
+		failed, total := countSubtests(pkg, group.root.Test, group.subtests)
+		fmt.Fprintf(out, "=== %s: %s %s (%d/%d subtests failed)\n",
+			conf.prefix,
+			RelativePackagePath(group.root.Package),
+			group.root.Test,
+			failed, total)
+		if group.hasRoot {
+			writeOutputLines(out, pkg.OutputLines(group.root), conf)
+		}
+		for _, tc := range group.subtests {
+			fmt.Fprintf(out, "    === %s: %s (%s)\n",
+				conf.prefix, tc.Test, FormatDurationAsSeconds(tc.Elapsed, 2))
+			writeOutputLines(out, pkg.OutputLines(tc), conf)
+		}
+		fmt.Fprintln(out)
+	}
+}
+
+func writeOutputLines(out io.Writer, lines []string, conf testCaseFormatConfig) {
+	if conf.hideOutput {
+		return
+	}
+	for _, line := range lines {
+		if isRunLine(line) || conf.filter(line) {
+			continue
+		}
+		fmt.Fprint(out, line)
+	}
+}
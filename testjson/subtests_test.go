@@ -0,0 +1,62 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPrintSummaryWithGroupSubtests(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total: 5,
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestTable/a"},
+					{Package: "example.com/one", Test: "TestTable/b/nested"},
+					{Package: "example.com/one", Test: "TestTable/b"},
+					{Package: "example.com/one", Test: "TestTable"},
+					{Package: "example.com/one", Test: "TestOther"},
+				},
+				Passed: []TestCase{
+					{Package: "example.com/one", Test: "TestTable/c"},
+				},
+				output: map[string][]string{
+					"TestTable/a": multiLine("=== RUN   TestTable/a\n" +
+						"    one_test.go:10: want 1\n" +
+						"    --- FAIL: TestTable/a (0.00s)\n"),
+					"TestTable/b/nested": multiLine("    one_test.go:12: want 2\n"),
+					"TestTable":          multiLine("--- FAIL: TestTable (0.00s)\n"),
+					"TestOther":          multiLine("    one_test.go:20: broken\n"),
+				},
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	err := PrintSummary(out, exec, SummarizeFailed|SummarizeGroupSubtests)
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: one TestTable (2/3 subtests failed)
+    === FAIL: TestTable/a (0.00s)
+    one_test.go:10: want 1
+    --- FAIL: TestTable/a (0.00s)
+    === FAIL: TestTable/b/nested (0.00s)
+    one_test.go:12: want 2
+    === FAIL: TestTable/b (0.00s)
+
+=== FAIL: one TestOther (0.00s)
+    one_test.go:20: broken
+
+
+DONE 5 tests, 5 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
@@ -3,6 +3,8 @@ package testjson
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 	"unicode"
@@ -20,33 +22,154 @@ const (
 	SummarizeSkipped
 	SummarizeFailed
 	SummarizeErrors
-	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors
+	SummarizeAll = SummarizeSkipped | SummarizeFailed | SummarizeErrors |
+		SummarizeRaces | SummarizePanics | SummarizeTimeouts
 )
 
+// SummarizeRaces, SummarizePanics, and SummarizeTimeouts print the tests with
+// output from the race detector, the tests which panicked, and the packages
+// which were stopped by the -timeout flag. They are included in SummarizeAll.
+// nolint: golint
+const (
+	SummarizeRaces Summary = 1 << (iota + 15)
+	SummarizePanics
+	SummarizeTimeouts
+)
+
+// SummarizeDedupeFailures is not a section of the summary. When it is set the
+// failed tests with the same normalized output are printed as a single entry
+// in the failed section. It is not included in SummarizeAll.
+const SummarizeDedupeFailures Summary = 1 << 8
+
+// SummarizeSkipReasons is not a section of the summary. When it is set the
+// skipped section prints a single line for each skipped test, with the
+// message passed to t.Skip. It is not included in SummarizeAll.
+const SummarizeSkipReasons Summary = 1 << 9
+
+// SummarizeCoverage prints the coverage of each package, from the output of
+// go test -cover. It is not included in SummarizeAll.
+const SummarizeCoverage Summary = 1 << 10
+
+// SummarizeSortFailures is not a section of the summary. When it is set the
+// failed tests are sorted by package and test name, instead of the order in
+// which they failed, so that the summary of two runs can be compared. It is
+// not included in SummarizeAll.
+const SummarizeSortFailures Summary = 1 << 11
+
+// SummarizeDoneFirst is not a section of the summary. When it is set the DONE
+// line is printed before the sections, so that the counts are at the top of
+// the summary when the output is read by scrolling up. It is not included in
+// SummarizeAll.
+const SummarizeDoneFirst Summary = 1 << 12
+
+// SummarizeGroupSubtests is not a section of the summary. When it is set the
+// failed subtests are printed under their top level test, with the number of
+// subtests which failed. It is ignored when SummarizeDedupeFailures is set. It
+// is not included in SummarizeAll.
+const SummarizeGroupSubtests Summary = 1 << 13
+
+// SummarizeHideOutput is not a section of the summary. When it is set the
+// failed and skipped sections print only the names of the tests, without the
+// output of the tests. It is not included in SummarizeAll.
+const SummarizeHideOutput Summary = 1 << 14
+
 // PrintSummary of a test Execution. Prints a section for each summary type
 // followed by a DONE line.
 func PrintSummary(out io.Writer, execution *Execution, opts Summary) error {
+	done := fmt.Sprintf("\n%s %s%d tests%s%s%s%s in %s%s\n",
+		"DONE", // TODO: maybe color this?
+		formatRunCount(execution.Runs()),
+		execution.Total(),
+		formatTestCount(len(execution.Skipped()), "skipped", ""),
+		formatTestCount(len(execution.Failed()), "failure", "s"),
+		formatTestCount(len(execution.BuildFailures()), "build error", "s"),
+		formatTestCount(countErrors(execution.Errors()), "error", "s"),
+		FormatDurationAsSeconds(execution.Elapsed(), 3),
+		formatTestTime(execution))
+	writeSummary(out, execution, opts, done)
+	return nil
+}
+
+// formatTestTime returns the wall time label and the sum of test time, or an
+// empty string if the tests did not record an elapsed time.
+func formatTestTime(execution *Execution) string {
+	testTime := execution.TestTime()
+	if testTime == 0 {
+		return ""
+	}
+	return " wall time, " + FormatDurationAsSeconds(testTime, 3) + " sum of test time"
+}
+
+// PrintCompactSummary of a test Execution. Prints a section for each summary
+// type followed by a DONE line which always includes every count, so that
+// the line has the same format for every run. Ex:
+//
+//	DONE 342 tests, 3 failures, 1 skipped, 0 errors in 12.400s
+func PrintCompactSummary(out io.Writer, execution *Execution, opts Summary) error {
+	done := fmt.Sprintf("\nDONE %d tests, %d failures, %d skipped, %d errors in %s\n",
+		execution.Total(),
+		len(execution.Failed()),
+		len(execution.Skipped()),
+		countErrors(execution.Errors()),
+		FormatDurationAsSeconds(execution.Elapsed(), 3))
+	writeSummary(out, execution, opts, done)
+	return nil
+}
+
+// writeSummary prints the summary sections and the DONE line, in the order
+// selected by SummarizeDoneFirst.
+func writeSummary(out io.Writer, execution *Execution, opts Summary, done string) {
+	if opts&SummarizeDoneFirst != 0 {
+		fmt.Fprint(out, done)
+		writeSummarySections(out, execution, opts)
+		return
+	}
+	writeSummarySections(out, execution, opts)
+	fmt.Fprint(out, done)
+}
+
+// writeSummarySections prints the summary sections selected by opts.
+func writeSummarySections(out io.Writer, execution *Execution, opts Summary) {
 	if opts&SummarizeSkipped != 0 {
-		writeTestCaseSummary(out, execution, formatSkipped())
+		skipped := formatSkipped()
+		skipped.reasons = opts&SummarizeSkipReasons != 0
+		skipped.hideOutput = opts&SummarizeHideOutput != 0
+		writeTestCaseSummary(out, execution, skipped)
 	}
 	if opts&SummarizeFailed != 0 {
-		writeTestCaseSummary(out, execution, formatFailed())
+		failed := formatFailed()
+		failed.dedupe = opts&SummarizeDedupeFailures != 0
+		failed.subtests = opts&SummarizeGroupSubtests != 0
+		failed.hideOutput = opts&SummarizeHideOutput != 0
+		if opts&SummarizeSortFailures != 0 {
+			failed.getter = sortedFailed
+		}
+		writeTestCaseSummary(out, execution, failed)
 	}
 
-	errors := execution.Errors()
 	if opts&SummarizeErrors != 0 {
-		writeErrorSummary(out, errors)
+		writeBuildErrorSummary(out, execution.BuildFailures())
+		writeErrorSummary(out, execution.Errors())
 	}
+	if opts&SummarizeRaces != 0 {
+		writeRaceSummary(out, execution)
+	}
+	if opts&SummarizePanics != 0 {
+		writePanicSummary(out, execution)
+	}
+	if opts&SummarizeTimeouts != 0 {
+		writeTimeoutSummary(out, execution)
+	}
+	if opts&SummarizeCoverage != 0 {
+		writeCoverageSummary(out, execution)
+	}
+}
 
-	fmt.Fprintf(out, "\n%s %d tests%s%s%s in %s\n",
-		"DONE", // TODO: maybe color this?
-		execution.Total(),
-		formatTestCount(len(execution.Skipped()), "skipped", ""),
-		formatTestCount(len(execution.Failed()), "failure", "s"),
-		formatTestCount(countErrors(errors), "error", "s"),
-		FormatDurationAsSeconds(execution.Elapsed(), 3))
-
-	return nil
+func formatRunCount(runs int) string {
+	if runs <= 1 {
+		return ""
+	}
+	return fmt.Sprintf("%d runs, ", runs)
 }
 
 func formatTestCount(count int, category string, pluralize string) string {
@@ -65,6 +188,18 @@ func FormatDurationAsSeconds(d time.Duration, precision int) string {
 	return fmt.Sprintf("%.[2]*[1]fs", d.Seconds(), precision)
 }
 
+// writeBuildErrorSummary prints the name of each package which failed to
+// build. The compiler errors are printed in the errors section.
+func writeBuildErrorSummary(out io.Writer, packages []string) {
+	if len(packages) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.MagentaString("\n=== Build errors"))
+	for _, pkg := range packages {
+		fmt.Fprintln(out, RelativePackagePath(pkg))
+	}
+}
+
 func writeErrorSummary(out io.Writer, errors []string) {
 	if len(errors) > 0 {
 		fmt.Fprintln(out, color.MagentaString("\n=== Errors"))
@@ -74,6 +209,36 @@ func writeErrorSummary(out io.Writer, errors []string) {
 	}
 }
 
+// raceMarker is printed by the race detector when it finds a data race.
+const raceMarker = "WARNING: DATA RACE"
+
+// writeRaceSummary prints the name of each failed test with output from the
+// race detector. Nothing is printed if no races were detected.
+func writeRaceSummary(out io.Writer, execution *Execution) {
+	var races []TestCase
+	for _, tc := range execution.Failed() {
+		if hasRace(execution.Package(tc.Package).OutputLines(tc)) {
+			races = append(races, tc)
+		}
+	}
+	if len(races) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.RedString("\n=== Race detected in:"))
+	for _, tc := range races {
+		fmt.Fprintln(out, strings.TrimSpace(RelativePackagePath(tc.Package)+" "+tc.Test))
+	}
+}
+
+func hasRace(lines []string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, raceMarker) {
+			return true
+		}
+	}
+	return false
+}
+
 // countErrors in stderr lines. Build errors may include multiple lines where
 // subsequent lines are indented.
 // FIXME: Panics will include multiple lines, and are still overcounted.
@@ -94,20 +259,88 @@ func writeTestCaseSummary(out io.Writer, execution *Execution, conf testCaseForm
 		return
 	}
 	fmt.Fprintln(out, "\n=== "+conf.header)
+	if conf.reasons {
+		for _, tc := range testCases {
+			writeSkipReason(out, execution, conf, tc)
+		}
+		return
+	}
+	if conf.subtests && !conf.dedupe {
+		writeSubtestGroups(out, execution, conf, testCases)
+		return
+	}
+	if !conf.dedupe {
+		for _, tc := range testCases {
+			writeTestCase(out, execution, conf, tc)
+		}
+		return
+	}
+	for _, group := range groupTestCases(execution, testCases, conf) {
+		writeTestCase(out, execution, conf, group[0])
+		if len(group) > 1 {
+			fmt.Fprintf(out, "=== %s: %s with the same output: %s\n\n",
+				conf.prefix, pluralize(len(group)-1, "more test"), formatTestNames(group[1:]))
+		}
+	}
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+func formatTestNames(testCases []TestCase) string {
+	names := make([]string, 0, len(testCases))
 	for _, tc := range testCases {
-		fmt.Fprintf(out, "=== %s: %s %s (%s)\n",
-			conf.prefix,
-			relativePackagePath(tc.Package),
-			tc.Test,
-			FormatDurationAsSeconds(tc.Elapsed, 2))
-		for _, line := range execution.OutputLines(tc.Package, tc.Test) {
-			if isRunLine(line) || conf.filter(line) {
-				continue
-			}
-			fmt.Fprint(out, line)
+		names = append(names, RelativePackagePath(tc.Package)+" "+tc.Test)
+	}
+	return strings.Join(names, ", ")
+}
+
+func writeTestCase(out io.Writer, execution *Execution, conf testCaseFormatConfig, tc TestCase) {
+	fmt.Fprintf(out, "=== %s: %s %s (%s)\n",
+		conf.prefix,
+		RelativePackagePath(tc.Package),
+		tc.Test,
+		FormatDurationAsSeconds(tc.Elapsed, 2))
+	if conf.hideOutput {
+		return
+	}
+	writeOutputLines(out, execution.Package(tc.Package).OutputLines(tc), conf)
+	fmt.Fprintln(out)
+}
+
+// writeSkipReason prints a single line with the name of the test and the
+// reason the test was skipped.
+func writeSkipReason(out io.Writer, execution *Execution, conf testCaseFormatConfig, tc TestCase) {
+	reason := skipReason(execution.Package(tc.Package).OutputLines(tc))
+	if reason != "" {
+		reason = ": " + reason
+	}
+	fmt.Fprintf(out, "=== %s: %s %s%s\n",
+		conf.prefix, RelativePackagePath(tc.Package), tc.Test, reason)
+}
+
+// skipLocation matches the file and line number which prefix the message
+// logged by t.Skip.
+var skipLocation = regexp.MustCompile(`^\S+\.go:\d+: ?`)
+
+// skipReason returns the message logged by t.Skip, from the output of a
+// skipped test. Multiple lines are joined with a space.
+func skipReason(lines []string) string {
+	var reason []string
+	for _, line := range lines {
+		if isRunLine(line) || strings.HasPrefix(line, "--- SKIP: ") {
+			continue
+		}
+		line = skipLocation.ReplaceAllString(strings.TrimSpace(line), "")
+		if line != "" {
+			reason = append(reason, line)
 		}
-		fmt.Fprintln(out)
 	}
+	return strings.Join(reason, " ")
 }
 
 type testCaseFormatConfig struct {
@@ -115,6 +348,14 @@ type testCaseFormatConfig struct {
 	prefix string
 	filter func(string) bool
 	getter func(*Execution) []TestCase
+	// dedupe groups test cases with the same normalized output.
+	dedupe bool
+	// reasons prints a single line with the skip reason for each test case.
+	reasons bool
+	// subtests groups failed subtests under their top level test.
+	subtests bool
+	// hideOutput prints only the names of the test cases.
+	hideOutput bool
 }
 
 func formatFailed() testCaseFormatConfig {
@@ -131,6 +372,20 @@ func formatFailed() testCaseFormatConfig {
 	}
 }
 
+// sortedFailed returns the failed test cases sorted by package and test name.
+// Test cases with the same name, from more than one run, are kept in the order
+// of the runs.
+func sortedFailed(execution *Execution) []TestCase {
+	failed := execution.Failed()
+	sort.SliceStable(failed, func(i, j int) bool {
+		if failed[i].Package != failed[j].Package {
+			return failed[i].Package < failed[j].Package
+		}
+		return failed[i].Test < failed[j].Test
+	})
+	return failed
+}
+
 func formatSkipped() testCaseFormatConfig {
 	withColor := color.YellowString
 	return testCaseFormatConfig{
@@ -30,6 +30,279 @@ func TestPrintSummaryNoFailures(t *testing.T) {
 	assert.Equal(t, out.String(), expected)
 }
 
+func TestPrintSummaryWithReruns(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		runs:    2,
+		packages: map[string]*Package{
+			"foo": {Total: 4},
+		},
+	}
+	fake.Advance(2 * time.Second)
+	err := PrintSummary(out, exec, SummarizeNone)
+	assert.NilError(t, err)
+
+	expected := "\nDONE 2 runs, 4 tests in 2.000s\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithTestTime(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"foo": {
+				Total: 3,
+				Passed: []TestCase{
+					{Package: "foo", Test: "TestOne", Elapsed: 3 * time.Second},
+					{Package: "foo", Test: "TestOne/sub", Elapsed: 2 * time.Second},
+					{Package: "foo", Test: "TestTwo", Elapsed: 4 * time.Second},
+				},
+			},
+		},
+	}
+	fake.Advance(2 * time.Second)
+	exec.finished = fake.Now()
+	fake.Advance(time.Minute)
+	err := PrintSummary(out, exec, SummarizeNone)
+	assert.NilError(t, err)
+
+	expected := "\nDONE 3 tests in 2.000s wall time, 7.000s sum of test time\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintCompactSummary(t *testing.T) {
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"foo": {
+				Total:   4,
+				Failed:  []TestCase{{Package: "foo", Test: "TestOne"}},
+				Skipped: []TestCase{{Package: "foo", Test: "TestTwo"}},
+			},
+		},
+	}
+	fake.Advance(12400 * time.Millisecond)
+	err := PrintCompactSummary(out, exec, SummarizeNone)
+	assert.NilError(t, err)
+
+	expected := "\nDONE 4 tests, 1 failures, 1 skipped, 0 errors in 12.400s\n"
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithSkipReasons(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total: 2,
+				Skipped: []TestCase{
+					{Package: "example.com/one", Test: "TestNoReason"},
+					{Package: "example.com/one", Test: "TestReason"},
+				},
+				output: map[string][]string{
+					"TestNoReason": multiLine(
+						"=== RUN   TestNoReason\n--- SKIP: TestNoReason (0.00s)\n    one_test.go:10: \n"),
+					"TestReason": multiLine(`=== RUN   TestReason
+--- SKIP: TestReason (0.00s)
+    one_test.go:14: requires docker
+        to be running
+`),
+				},
+			},
+		},
+	}
+	err := PrintSummary(out, exec, SummarizeSkipped|SummarizeSkipReasons)
+	assert.NilError(t, err)
+
+	expected := `
+=== Skipped
+=== SKIP: one TestNoReason
+=== SKIP: one TestReason: requires docker to be running
+
+DONE 2 tests, 2 skipped in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithDoneFirst(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total:   2,
+				Skipped: []TestCase{{Package: "example.com/one", Test: "TestSkip"}},
+			},
+		},
+	}
+	err := PrintSummary(out, exec, SummarizeSkipped|SummarizeDoneFirst)
+	assert.NilError(t, err)
+
+	expected := `
+DONE 2 tests, 1 skipped in 0.000s
+
+=== Skipped
+=== SKIP: one TestSkip (0.00s)
+
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithSortFailures(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total: 3,
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestC"},
+					{Package: "example.com/one", Test: "TestA/sub"},
+					{Package: "example.com/one", Test: "TestA"},
+				},
+				output: map[string][]string{},
+				action: ActionFail,
+			},
+		},
+	}
+	err := PrintSummary(out, exec, SummarizeFailed|SummarizeSortFailures)
+	assert.NilError(t, err)
+
+	expected := `
+=== Failed
+=== FAIL: one TestA (0.00s)
+
+=== FAIL: one TestA/sub (0.00s)
+
+=== FAIL: one TestC (0.00s)
+
+
+DONE 3 tests, 3 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithHideOutput(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total:   3,
+				Failed:  []TestCase{{Package: "example.com/one", Test: "TestA"}},
+				Skipped: []TestCase{{Package: "example.com/one", Test: "TestB"}},
+				output: map[string][]string{
+					"TestA": {"=== RUN   TestA\n", "    a_test.go:9: failed\n", "--- FAIL: TestA (0.00s)\n"},
+					"TestB": {"    b_test.go:9: skipped\n"},
+				},
+				action: ActionFail,
+			},
+		},
+	}
+	err := PrintSummary(out, exec, SummarizeAll|SummarizeHideOutput)
+	assert.NilError(t, err)
+
+	expected := `
+=== Skipped
+=== SKIP: one TestB (0.00s)
+
+=== Failed
+=== FAIL: one TestA (0.00s)
+
+DONE 3 tests, 1 skipped, 1 failure in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithRace(t *testing.T) {
+	defer patchPkgPathPrefix("example.com")()
+	fake, reset := patchClock()
+	defer reset()
+
+	out := new(bytes.Buffer)
+	exec := &Execution{
+		started: fake.Now(),
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total: 3,
+				Failed: []TestCase{
+					{Package: "example.com/one", Test: "TestRace"},
+					{Package: "example.com/one", Test: "TestFail"},
+				},
+				output: map[string][]string{
+					"TestRace": multiLine(`=== RUN   TestRace
+==================
+WARNING: DATA RACE
+Write at 0x00c000014118 by goroutine 8:
+==================
+--- FAIL: TestRace (0.00s)
+    testing.go:809: race detected during execution of test
+`),
+					"TestFail": multiLine("=== RUN   TestFail\n--- FAIL: TestFail (0.00s)\n"),
+				},
+				action: ActionFail,
+			},
+		},
+	}
+	err := PrintSummary(out, exec, SummarizeRaces)
+	assert.NilError(t, err)
+
+	expected := `
+=== Race detected in:
+one TestRace
+
+DONE 3 tests, 2 failures in 0.000s
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestPrintSummaryWithoutRaces(t *testing.T) {
+	exec := &Execution{
+		packages: map[string]*Package{
+			"example.com/one": {
+				Total:  1,
+				Failed: []TestCase{{Package: "example.com/one", Test: "TestRace"}},
+				output: map[string][]string{
+					"TestRace": multiLine("WARNING: DATA RACE\n--- FAIL: TestRace (0.00s)\n"),
+				},
+				action: ActionFail,
+			},
+		},
+	}
+	out := new(bytes.Buffer)
+	assert.NilError(t, PrintSummary(out, exec, SummarizeAll&^SummarizeRaces))
+	assert.Assert(t, !strings.Contains(out.String(), "Race detected"), out.String())
+}
+
 func TestPrintSummaryWithFailures(t *testing.T) {
 	defer patchPkgPathPrefix("example.com")()
 	fake, reset := patchClock()
@@ -103,6 +376,7 @@ Some stdout/stderr here
 		errors: []string{
 			"pkg/file.go:99:12: missing ',' before newline",
 		},
+		buildFailures: []string{"example.com/project/pkg"},
 	}
 	fake.Advance(34123111 * time.Microsecond)
 	err := PrintSummary(out, exec, SummarizeAll)
@@ -128,11 +402,14 @@ Some stdout/stderr here
 === FAIL: project/pkg/more TestAlbatross (0.04s)
 
 
+=== Build errors
+project/pkg
+
 === Errors
 pkg/file.go:99:12: missing ',' before newline
 
-DONE 13 tests, 1 skipped, 4 failures, 1 error in 34.123s
-`
+DONE 13 tests, 1 skipped, 4 failures, 1 build error, 1 error ` +
+		"in 34.123s wall time, 1.463s sum of test time\n"
 	assert.Equal(t, out.String(), expected)
 }
 
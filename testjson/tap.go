@@ -0,0 +1,64 @@
+package testjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tapFormat prints a line for each test result in the Test Anything Protocol
+// format. The plan is printed at the end of the run by TAPPlan because the
+// number of tests is not known until all the tests are complete.
+func tapFormat(event TestEvent, exec *Execution) (string, error) {
+	name := RelativePackagePath(event.Package)
+	if !event.PackageEvent() {
+		name += "." + event.Test
+	}
+
+	switch {
+	case event.PackageEvent() && event.Action == ActionFail:
+		if !exec.Package(event.Package).TestMainFailed() {
+			return "", nil
+		}
+		return formatTAPFailure(tapResultCount(exec), name, exec.Output(event.Package, "")), nil
+	case event.PackageEvent():
+		return "", nil
+	case event.Action == ActionPass:
+		return fmt.Sprintf("ok %d - %s\n", tapResultCount(exec), name), nil
+	case event.Action == ActionSkip:
+		return fmt.Sprintf("ok %d - %s # SKIP\n", tapResultCount(exec), name), nil
+	case event.Action == ActionFail:
+		output := exec.Output(event.Package, event.Test)
+		return formatTAPFailure(tapResultCount(exec), name, output), nil
+	}
+	return "", nil
+}
+
+func formatTAPFailure(num int, name string, output string) string {
+	result := fmt.Sprintf("not ok %d - %s\n", num, name)
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if line == "" || isRunLine(line) {
+			continue
+		}
+		result += "# " + strings.TrimRight(line, "\n") + "\n"
+	}
+	return result
+}
+
+// tapResultCount returns the number of test results in the Execution. A
+// package which failed without a test failure counts as a single result.
+func tapResultCount(exec *Execution) int {
+	count := 0
+	for _, pkg := range exec.packages {
+		count += len(pkg.Passed) + len(pkg.Failed) + len(pkg.Skipped)
+		if pkg.TestMainFailed() {
+			count++
+		}
+	}
+	return count
+}
+
+// TAPPlan returns the Test Anything Protocol plan line for all the test
+// results in the Execution.
+func TAPPlan(exec *Execution) string {
+	return fmt.Sprintf("1..%d\n", tapResultCount(exec))
+}
@@ -0,0 +1,101 @@
+package testjson
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// timeoutPatterns match the lines printed when a test binary is stopped by the
+// -timeout of go test, and capture the timeout. The panic is printed by the
+// test binary, and the "Test killed" line by go test when the binary does not
+// exit after the panic.
+var timeoutPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^panic: test timed out after (\S+)`),
+	regexp.MustCompile(`^\*\*\* Test killed.*: ran too long \(([^)]+)\)`),
+}
+
+// timeout is a package which was stopped by the -timeout of go test.
+type timeout struct {
+	pkg string
+	// tests are the tests which were running when the package timed out.
+	tests   []string
+	timeout string
+}
+
+// parseTimeout returns the timeout from the output of a test or package, and
+// the names of the running tests printed after the timeout panic. It returns
+// false if the output does not include a timeout.
+func parseTimeout(lines []string) (string, []string, bool) {
+	var value string
+	var running []string
+	var inRunning bool
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case value == "":
+			value = matchTimeout(line)
+		case line == "running tests:":
+			inRunning = true
+		case inRunning && strings.HasPrefix(line, "\t"):
+			running = append(running, strings.Fields(line)[0])
+		default:
+			inRunning = false
+		}
+	}
+	return value, running, value != ""
+}
+
+func matchTimeout(line string) string {
+	for _, pattern := range timeoutPatterns {
+		if match := pattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+func isTimeoutLine(line string) bool {
+	return matchTimeout(strings.TrimSuffix(line, "\n")) != ""
+}
+
+// timeouts returns the packages which were stopped by the -timeout of go
+// test. The timeout is printed in the output of the test which was running,
+// or in the output of the package.
+func timeouts(execution *Execution) []timeout {
+	var result []timeout
+	for _, name := range execution.Packages() {
+		pkg := execution.Package(name)
+		candidates := append([]TestCase{{Package: name}}, pkg.Failed...)
+		for _, tc := range candidates {
+			value, running, ok := parseTimeout(pkg.OutputLines(tc))
+			if !ok {
+				continue
+			}
+			if len(running) == 0 && tc.Test != "" {
+				running = []string{tc.Test}
+			}
+			result = append(result, timeout{pkg: name, tests: running, timeout: value})
+			break
+		}
+	}
+	return result
+}
+
+// writeTimeoutSummary prints each package which was stopped by the -timeout
+// of go test, with the tests which were running and the timeout. Nothing is
+// printed if no packages timed out.
+func writeTimeoutSummary(out io.Writer, execution *Execution) {
+	result := timeouts(execution)
+	if len(result) == 0 {
+		return
+	}
+	fmt.Fprintln(out, color.RedString("\n=== Timed out"))
+	for _, t := range result {
+		name := strings.TrimSpace(RelativePackagePath(t.pkg) + " " + strings.Join(t.tests, ", "))
+		fmt.Fprintf(out, "%s (test timed out after %s)\n", name, t.timeout)
+	}
+}
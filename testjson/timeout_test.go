@@ -0,0 +1,45 @@
+package testjson
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestWriteTimeoutSummary(t *testing.T) {
+	defer patchPkgPathPrefix("github.com/gotestyourself/gotestyourself")()
+	shim := newFakeHandler(standardQuietFormat, "go-test-json-with-timeout")
+	exec, err := ScanTestOutput(shim.Config(t))
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	writeTimeoutSummary(out, exec)
+	expected := `
+=== Timed out
+testjson/internal/stub TestTimeout (test timed out after 10ms)
+`
+	assert.Equal(t, out.String(), expected)
+	assert.Equal(t, len(exec.Panicked()), 0)
+}
+
+func TestParseTimeout(t *testing.T) {
+	value, running, ok := parseTimeout([]string{
+		"panic: test timed out after 1m0s\n",
+		"running tests:\n",
+		"\tTestSlow (1m0s)\n",
+		"\tTestSlower (59s)\n",
+		"\n",
+		"goroutine 17 [running]:\n",
+	})
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "1m0s")
+	assert.DeepEqual(t, running, []string{"TestSlow", "TestSlower"})
+
+	value, _, ok = parseTimeout([]string{"*** Test killed with quit: ran too long (11m0s).\n"})
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "11m0s")
+
+	_, _, ok = parseTimeout([]string{"    foo_test.go:12: panic: test timed out after 1s\n"})
+	assert.Assert(t, !ok)
+}